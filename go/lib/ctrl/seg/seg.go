@@ -21,6 +21,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
@@ -142,6 +143,45 @@ func (ps *PathSegment) ContainsInterface(ia addr.IA, ifid common.IFIDType) bool
 	return false
 }
 
+// MinExpiry returns the earliest expiration time of all hop fields in the
+// segment. Up to this point in time, the full segment can be used.
+func (ps *PathSegment) MinExpiry() (time.Time, error) {
+	return ps.expiry(spath.MaxTTL*time.Second, func(hfTTL, ttl time.Duration) bool {
+		return hfTTL < ttl
+	})
+}
+
+// MaxExpiry returns the latest expiration time of all hop fields in the
+// segment. After this point in time, no part of the segment can be used.
+func (ps *PathSegment) MaxExpiry() (time.Time, error) {
+	return ps.expiry(0, func(hfTTL, ttl time.Duration) bool {
+		return hfTTL > ttl
+	})
+}
+
+func (ps *PathSegment) expiry(initTTL time.Duration,
+	replace func(hfTTL, ttl time.Duration) bool) (time.Time, error) {
+
+	info, err := ps.InfoF()
+	if err != nil {
+		return time.Time{}, err
+	}
+	ttl := initTTL
+	for _, asEntry := range ps.ASEntries {
+		for _, hopEntry := range asEntry.HopEntries {
+			hf, err := hopEntry.HopField()
+			if err != nil {
+				return time.Time{}, err
+			}
+			hfTTL := time.Duration(hf.ExpTime) * spath.ExpTimeUnit * time.Second
+			if replace(hfTTL, ttl) {
+				ttl = hfTTL
+			}
+		}
+	}
+	return info.Timestamp().Add(ttl), nil
+}
+
 // walkHopEntries iterates through the hop entries of asEntries, checking that
 // the hop fields within can be parsed. If an parse error is found, the
 // function immediately returns with an error.
@@ -0,0 +1,92 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cleaner periodically deletes expired entries from a database, e.g.,
+// expired path segments from a pathdb.DB or expired revocations from a
+// revocation cache.
+package cleaner
+
+import (
+	"context"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/log"
+)
+
+// ExpiredDeleter deletes all entries that expired before now. It returns the
+// number of deleted entries.
+type ExpiredDeleter func(ctx context.Context, now time.Time) (int, error)
+
+// Cleaner periodically calls an ExpiredDeleter.
+type Cleaner struct {
+	deleter   ExpiredDeleter
+	subsystem string
+	interval  time.Duration
+	logger    log.Logger
+	stopC     chan struct{}
+}
+
+// New creates a cleaner that calls deleter every interval. The subsystem names
+// the cleaned database in logs and metrics. Call Run to start it.
+func New(deleter ExpiredDeleter, subsystem string, interval time.Duration,
+	logger log.Logger) *Cleaner {
+
+	return &Cleaner{
+		deleter:   deleter,
+		subsystem: subsystem,
+		interval:  interval,
+		logger:    logger.New("subsystem", subsystem),
+		stopC:     make(chan struct{}),
+	}
+}
+
+// Run deletes expired entries until Stop is called. Run blocks, so it is
+// usually started in its own goroutine.
+func (c *Cleaner) Run() {
+	defer log.LogPanicAndExit()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	c.logger.Info("Cleaner starting", "interval", c.interval)
+Top:
+	for {
+		select {
+		case <-c.stopC:
+			break Top
+		case now := <-ticker.C:
+			c.clean(now)
+		}
+	}
+	c.logger.Info("Cleaner stopping")
+}
+
+// Stop terminates Run. Stop must be called at most once.
+func (c *Cleaner) Stop() {
+	close(c.stopC)
+}
+
+func (c *Cleaner) clean(now time.Time) {
+	ctx, cancelF := context.WithTimeout(context.Background(), c.interval)
+	defer cancelF()
+	incCounter(runs, c.subsystem, 1)
+	deleted, err := c.deleter(ctx, now)
+	if err != nil {
+		incCounter(errors, c.subsystem, 1)
+		c.logger.Error("Unable to delete expired entries", "err", err)
+		return
+	}
+	incCounter(expired, c.subsystem, deleted)
+	if deleted > 0 {
+		c.logger.Debug("Deleted expired entries", "count", deleted)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cleaner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/log"
+)
+
+func TestCleaner(t *testing.T) {
+	Convey("The cleaner should call the deleter until it is stopped", t, func() {
+		hasDeadline := make(chan bool, 10)
+		deleter := func(ctx context.Context, now time.Time) (int, error) {
+			_, ok := ctx.Deadline()
+			hasDeadline <- ok
+			return 1, nil
+		}
+		c := New(deleter, "test", 10*time.Millisecond, log.Root())
+		done := make(chan struct{})
+		go func() {
+			c.Run()
+			close(done)
+		}()
+		for i := 0; i < 2; i++ {
+			select {
+			case ok := <-hasDeadline:
+				SoMsg("deadline", ok, ShouldBeTrue)
+			case <-time.After(time.Second):
+				t.Fatal("Deleter not called")
+			}
+		}
+		c.Stop()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Cleaner did not stop")
+		}
+	})
+}
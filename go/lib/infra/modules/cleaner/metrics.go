@@ -0,0 +1,47 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cleaner
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/scionproto/scion/go/lib/prom"
+)
+
+var (
+	runs    *prometheus.CounterVec
+	errors  *prometheus.CounterVec
+	expired *prometheus.CounterVec
+)
+
+// InitMetrics registers the cleaner metrics, labeled by the subsystem of each
+// cleaner. If InitMetrics is not called, cleaners do not export metrics.
+func InitMetrics(namespace string, constLabels prometheus.Labels) {
+	newCV := func(name, help string) *prometheus.CounterVec {
+		v := prom.NewCounterVec(namespace, "cleaner", name, help, constLabels,
+			[]string{"subsystem"})
+		prometheus.MustRegister(v)
+		return v
+	}
+	runs = newCV("runs_total", "Number of expired entry cleanups.")
+	errors = newCV("errors_total", "Number of failed expired entry cleanups.")
+	expired = newCV("deleted_total", "Number of expired entries removed.")
+}
+
+func incCounter(cv *prometheus.CounterVec, subsystem string, n int) {
+	if cv != nil {
+		cv.WithLabelValues(subsystem).Add(float64(n))
+	}
+}
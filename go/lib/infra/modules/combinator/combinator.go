@@ -54,6 +54,15 @@ func Combine(src, dst addr.IA, ups, cores, downs []*seg.PathSegment) []*Path {
 	return FilterLongPaths(pathSlice)
 }
 
+// CombineWithPolicy constructs paths like Combine, drops paths that traverse
+// the same interfaces as a previous path (see FilterDuplicates), and returns
+// the remaining paths ordered and filtered according to policy.
+func CombineWithPolicy(src, dst addr.IA, ups, cores, downs []*seg.PathSegment,
+	policy Policy) []*Path {
+
+	return policy.Apply(FilterDuplicates(Combine(src, dst, ups, cores, downs)))
+}
+
 // InputSegment is a local representation of a path segment that includes the
 // segment's type.
 type InputSegment struct {
@@ -72,6 +81,7 @@ type Path struct {
 	Mtu        uint16
 	Interfaces []sciond.PathInterface
 	ExpTime    time.Time
+	Metadata   sciond.PathMetadata
 }
 
 func (p *Path) writeTestString(w io.Writer) {
@@ -87,6 +97,7 @@ func (p *Path) writeTestString(w io.Writer) {
 	for _, pi := range p.Interfaces {
 		fmt.Fprintf(w, "    %v\n", pi)
 	}
+	fmt.Fprintf(w, "  Metadata: %v\n", p.Metadata)
 }
 
 func (p *Path) reverseDownSegment() {
@@ -113,6 +124,25 @@ func (p *Path) computeExpTime() {
 	}
 }
 
+// computeMetadata derives the path metadata from the segments and the
+// aggregated interfaces. Must be called after aggregateInterfaces.
+func (p *Path) computeMetadata() {
+	p.Metadata = sciond.PathMetadata{
+		// Every inter-AS link contributes an egress and an ingress interface.
+		Hops: uint8(len(p.Interfaces) / 2),
+	}
+	isds := make(map[addr.ISD]struct{})
+	for _, pi := range p.Interfaces {
+		isds[pi.ISD_AS().I] = struct{}{}
+	}
+	p.Metadata.ISDs = uint8(len(isds))
+	for _, segment := range p.Segments {
+		if segment.InfoField.Peer {
+			p.Metadata.Peering = true
+		}
+	}
+}
+
 func (p *Path) WriteTo(w io.Writer) (int64, error) {
 	var total int64
 	for _, segment := range p.Segments {
@@ -230,3 +260,20 @@ func FilterLongPaths(paths []*Path) []*Path {
 	}
 	return newPaths
 }
+
+// FilterDuplicates returns a new slice containing only the first path of each
+// set of paths that traverse the same interfaces. Such paths differ only in
+// the segments they were built from.
+func FilterDuplicates(paths []*Path) []*Path {
+	var newPaths []*Path
+	seen := make(map[string]struct{})
+	for _, path := range paths {
+		key := fmt.Sprint(path.Interfaces)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		newPaths = append(newPaths, path)
+	}
+	return newPaths
+}
@@ -487,3 +487,22 @@ func writePaths(paths []*Path) *bytes.Buffer {
 	}
 	return buffer
 }
+
+func TestCombineWithPolicy(t *testing.T) {
+	g := graph.NewDefaultGraph()
+	src := xtest.MustParseIA("1-ff00:0:112")
+	dst := xtest.MustParseIA("1-ff00:0:110")
+	// Two up segments over the same interfaces yield two paths that differ
+	// only in the segment they were built from.
+	ups := []*seg.PathSegment{
+		g.Beacon([]common.IFIDType{1114, 1417}),
+		g.Beacon([]common.IFIDType{1114, 1417}),
+	}
+	Convey("Combine keeps paths over the same interfaces", t, func() {
+		SoMsg("paths", len(Combine(src, dst, ups, nil, nil)), ShouldEqual, 2)
+	})
+	Convey("CombineWithPolicy drops paths over the same interfaces", t, func() {
+		paths := CombineWithPolicy(src, dst, ups, nil, nil, Shortest)
+		SoMsg("paths", len(paths), ShouldEqual, 1)
+	})
+}
@@ -346,6 +346,7 @@ func (solution *PathSolution) GetFwdPathMetadata() *Path {
 	path.reverseDownSegment()
 	path.aggregateInterfaces()
 	path.computeExpTime()
+	path.computeMetadata()
 	return path
 }
 
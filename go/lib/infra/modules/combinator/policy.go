@@ -0,0 +1,116 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package combinator
+
+import (
+	"sort"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+)
+
+// Policy orders and filters the paths returned by Combine.
+type Policy interface {
+	// Apply returns the paths in order of preference. The input slice is
+	// ordered by weight and must not be modified.
+	Apply(paths []*Path) []*Path
+}
+
+// PolicyFunc is an adapter to allow the use of ordinary functions as Policy.
+type PolicyFunc func(paths []*Path) []*Path
+
+func (f PolicyFunc) Apply(paths []*Path) []*Path {
+	return f(paths)
+}
+
+var (
+	// Shortest keeps the order computed by Combine, i.e., paths with the
+	// fewest AS hops come first.
+	Shortest Policy = PolicyFunc(func(paths []*Path) []*Path {
+		return append([]*Path(nil), paths...)
+	})
+	// MostDisjoint orders paths such that each path shares as few interfaces
+	// as possible with the paths before it.
+	MostDisjoint Policy = PolicyFunc(mostDisjoint)
+	// FewestISDs orders paths by the number of ISDs they traverse.
+	FewestISDs Policy = sortBy(func(a, b *Path) bool {
+		return a.Metadata.ISDs < b.Metadata.ISDs
+	})
+	// PreferPeering orders paths that cross a peering link first.
+	PreferPeering Policy = sortBy(func(a, b *Path) bool {
+		return a.Metadata.Peering && !b.Metadata.Peering
+	})
+	// PreferShortcuts orders paths that take a shortcut and thus avoid
+	// transiting the core first. Peering paths are shortcuts, too.
+	PreferShortcuts Policy = sortBy(func(a, b *Path) bool {
+		return a.isShortcut() && !b.isShortcut()
+	})
+)
+
+// PolicyFromOrdering returns the policy implementing the ordering o.
+func PolicyFromOrdering(o sciond.PathOrdering) (Policy, error) {
+	switch o {
+	case sciond.OrderShortest:
+		return Shortest, nil
+	case sciond.OrderDisjoint:
+		return MostDisjoint, nil
+	case sciond.OrderFewestISDs:
+		return FewestISDs, nil
+	case sciond.OrderPeering:
+		return PreferPeering, nil
+	case sciond.OrderShortcuts:
+		return PreferShortcuts, nil
+	}
+	return nil, common.NewBasicError("Unsupported path ordering", nil, "ordering", o)
+}
+
+// sortBy returns a policy that stably sorts the paths according to less. Paths
+// that are equal according to less remain ordered by weight.
+func sortBy(less func(a, b *Path) bool) Policy {
+	return PolicyFunc(func(paths []*Path) []*Path {
+		sorted := append([]*Path(nil), paths...)
+		sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+		return sorted
+	})
+}
+
+func mostDisjoint(paths []*Path) []*Path {
+	return SelectDisjoint(paths, 0)
+}
+
+// SelectDisjoint returns up to k paths that share as few interfaces as
+// possible, in order of selection. Ties are broken in favor of paths earlier
+// in the input. If k is 0, all paths are returned.
+func SelectDisjoint(paths []*Path, k int) []*Path {
+	ifaces := make([][]sciond.PathInterface, len(paths))
+	for i, path := range paths {
+		ifaces[i] = path.Interfaces
+	}
+	var selected []*Path
+	for _, idx := range spathmeta.DisjointIndices(ifaces, k) {
+		selected = append(selected, paths[idx])
+	}
+	return selected
+}
+
+func (p *Path) isShortcut() bool {
+	for _, segment := range p.Segments {
+		if segment.InfoField.Shortcut {
+			return true
+		}
+	}
+	return false
+}
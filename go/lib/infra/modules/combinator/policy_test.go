@@ -0,0 +1,98 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package combinator
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/spath"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+// newTestPath returns a path over the interfaces ifIDs of AS 1-ff00:0:110.
+func newTestPath(weight int, meta sciond.PathMetadata, shortcut bool,
+	ifIDs ...common.IFIDType) *Path {
+
+	ia := xtest.MustParseIA("1-ff00:0:110")
+	p := &Path{
+		Weight: weight,
+		Segments: []*Segment{
+			{InfoField: &InfoField{InfoField: &spath.InfoField{Shortcut: shortcut}}},
+		},
+		Metadata: meta,
+	}
+	for _, ifID := range ifIDs {
+		p.Interfaces = append(p.Interfaces,
+			sciond.PathInterface{RawIsdas: ia.IAInt(), IfID: ifID})
+	}
+	return p
+}
+
+func TestPolicies(t *testing.T) {
+	a := newTestPath(2, sciond.PathMetadata{ISDs: 2}, false, 1, 2, 3, 4)
+	b := newTestPath(2, sciond.PathMetadata{ISDs: 1}, false, 1, 2, 3, 5)
+	c := newTestPath(3, sciond.PathMetadata{ISDs: 2, Peering: true}, true, 6, 7, 8, 9)
+	d := newTestPath(4, sciond.PathMetadata{ISDs: 1}, true, 1, 7, 8, 10)
+	paths := []*Path{a, b, c, d}
+
+	testCases := []struct {
+		Name     string
+		Ordering sciond.PathOrdering
+		Expected []*Path
+	}{
+		{"shortest", sciond.OrderShortest, []*Path{a, b, c, d}},
+		{"disjoint", sciond.OrderDisjoint, []*Path{a, c, b, d}},
+		{"fewest ISDs", sciond.OrderFewestISDs, []*Path{b, d, a, c}},
+		{"peering", sciond.OrderPeering, []*Path{c, a, b, d}},
+		{"shortcuts", sciond.OrderShortcuts, []*Path{c, d, a, b}},
+	}
+	Convey("Policies should order paths stably", t, func() {
+		for _, tc := range testCases {
+			Convey(tc.Name, func() {
+				policy, err := PolicyFromOrdering(tc.Ordering)
+				SoMsg("err", err, ShouldBeNil)
+				SoMsg("paths", policy.Apply(paths), ShouldResemble, tc.Expected)
+				SoMsg("input unchanged", paths, ShouldResemble, []*Path{a, b, c, d})
+			})
+		}
+	})
+	Convey("Unknown orderings should be rejected", t, func() {
+		_, err := PolicyFromOrdering(sciond.PathOrdering(42))
+		SoMsg("err", err, ShouldNotBeNil)
+	})
+}
+
+func TestSelectDisjoint(t *testing.T) {
+	Convey("SelectDisjoint should return at most k disjoint paths", t, func() {
+		a := newTestPath(2, sciond.PathMetadata{}, false, 1, 2, 3, 4)
+		b := newTestPath(2, sciond.PathMetadata{}, false, 1, 2, 3, 5)
+		c := newTestPath(3, sciond.PathMetadata{}, false, 6, 7, 8, 9)
+		SoMsg("k=2", SelectDisjoint([]*Path{a, b, c}, 2), ShouldResemble, []*Path{a, c})
+		SoMsg("empty", SelectDisjoint(nil, 2), ShouldBeEmpty)
+	})
+}
+
+func TestFilterDuplicates(t *testing.T) {
+	Convey("FilterDuplicates should keep the first path per interface list", t, func() {
+		a := newTestPath(2, sciond.PathMetadata{}, false, 1, 2)
+		b := newTestPath(3, sciond.PathMetadata{}, false, 1, 2)
+		c := newTestPath(3, sciond.PathMetadata{}, false, 1, 3)
+		SoMsg("paths", FilterDuplicates([]*Path{a, b, c}), ShouldResemble, []*Path{a, c})
+	})
+}
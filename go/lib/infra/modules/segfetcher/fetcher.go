@@ -12,9 +12,13 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package fetcher implements path segment fetching, verification and
+// Package segfetcher implements path segment fetching, verification and
 // combination logic for SCIOND.
-package fetcher
+//
+// The same logic can also be used in-process, without SCIOND. Service
+// implements sciond.Service on top of a Fetcher, so it can be used as the
+// backend of a path resolver (see pathmgr.New).
+package segfetcher
 
 import (
 	"bytes"
@@ -67,6 +71,7 @@ func NewFetcher(topo *topology.Topo, messenger infra.Messenger, pathDB *pathdb.D
 		pathDB:          pathDB,
 		trustStore:      trustStore,
 		revocationCache: revCache,
+		logger:          log.Root(),
 	}
 }
 
@@ -100,11 +105,15 @@ func (f *Fetcher) GetPaths(ctx context.Context, req *sciond.PathReq,
 	if req.Dst.IA().Eq(f.topology.ISD_AS) {
 		return f.buildSCIONDReply(nil, sciond.ErrorOk), nil
 	}
+	policy, err := combinator.PolicyFromOrdering(req.Flags.Ordering)
+	if err != nil {
+		return f.buildSCIONDReply(nil, sciond.ErrorInternal), err
+	}
 
 	if !req.Flags.Refresh {
 		// Try to build paths from local information first, if we don't have to
 		// get fresh segments.
-		paths, err := f.buildPathsFromDB(ctx, req)
+		paths, err := f.buildPathsFromDB(ctx, req, policy)
 		switch {
 		case ctx.Err() != nil:
 			return f.buildSCIONDReply(nil, sciond.ErrorNoPaths), nil
@@ -126,7 +135,7 @@ func (f *Fetcher) GetPaths(ctx context.Context, req *sciond.PathReq,
 	case <-subCtx.Done():
 	case <-ctx.Done():
 	}
-	paths, err := f.buildPathsFromDB(ctx, req)
+	paths, err := f.buildPathsFromDB(ctx, req, policy)
 	switch {
 	case ctx.Err() != nil:
 		return f.buildSCIONDReply(nil, sciond.ErrorNoPaths), nil
@@ -143,7 +152,7 @@ func (f *Fetcher) GetPaths(ctx context.Context, req *sciond.PathReq,
 		case <-subCtx.Done():
 		case <-ctx.Done():
 		}
-		paths, err := f.buildPathsFromDB(ctx, req)
+		paths, err := f.buildPathsFromDB(ctx, req, policy)
 		switch {
 		case ctx.Err() != nil:
 			return f.buildSCIONDReply(nil, sciond.ErrorNoPaths), nil
@@ -230,6 +239,7 @@ func (f *Fetcher) buildSCIONDReplyEntries(paths []*combinator.Path) []sciond.Pat
 				Mtu:        path.Mtu,
 				Interfaces: path.Interfaces,
 				ExpTime:    uint32(path.ExpTime.Unix()),
+				Metadata:   path.Metadata,
 			},
 			HostInfo: sciond.HostInfo{
 				Addrs: struct {
@@ -247,9 +257,10 @@ func (f *Fetcher) buildSCIONDReplyEntries(paths []*combinator.Path) []sciond.Pat
 }
 
 // buildPathsFromDB attempts to build paths only from information contained in the
-// local path database, taking the revocation cache into account.
-func (f *Fetcher) buildPathsFromDB(ctx context.Context,
-	req *sciond.PathReq) ([]*combinator.Path, error) {
+// local path database, taking the revocation cache into account. The paths are
+// ordered according to policy.
+func (f *Fetcher) buildPathsFromDB(ctx context.Context, req *sciond.PathReq,
+	policy combinator.Policy) ([]*combinator.Path, error) {
 
 	// Try to determine whether the destination AS is core or not
 	// FIXME(scrye): The trail below is incorrect. The tests are written with
@@ -319,8 +330,15 @@ func (f *Fetcher) buildPathsFromDB(ctx context.Context,
 			return nil, err
 		}
 	}
-	paths := combinator.Combine(req.Src.IA(), req.Dst.IA(), ups, cores, downs)
-	paths = f.filterRevokedPaths(paths)
+	// Drop revoked paths before ordering, so that they are not taken into
+	// account by policies that depend on the whole set of paths.
+	paths := combinator.CombineWithPolicy(req.Src.IA(), req.Dst.IA(), ups, cores, downs,
+		combinator.PolicyFunc(func(paths []*combinator.Path) []*combinator.Path {
+			return policy.Apply(f.filterRevokedPaths(paths))
+		}))
+	if req.Flags.Disjoint {
+		paths = combinator.SelectDisjoint(paths, int(req.MaxPaths))
+	}
 	return paths, nil
 }
 
@@ -328,8 +346,9 @@ func (f *Fetcher) getSegmentsFromDB(ctx context.Context, startsAt,
 	endsAt []addr.IA) ([]*seg.PathSegment, error) {
 
 	results, err := f.pathDB.Get(ctx, &query.Params{
-		StartsAt: startsAt,
-		EndsAt:   endsAt,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+		MinExpiry: time.Now(),
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,200 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package segfetcher
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	cache "github.com/patrickmn/go-cache"
+	. "github.com/smartystreets/goconvey/convey"
+	"zombiezen.com/go/capnproto2"
+	"zombiezen.com/go/capnproto2/pogs"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/crypto/trc"
+	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
+	"github.com/scionproto/scion/go/lib/ctrl/seg"
+	"github.com/scionproto/scion/go/lib/infra"
+	"github.com/scionproto/scion/go/lib/pathdb"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/topology"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/lib/xtest/graph"
+	"github.com/scionproto/scion/go/proto"
+)
+
+// testMessenger answers segment requests with a fixed set of segments. All
+// other calls panic.
+type testMessenger struct {
+	infra.Messenger
+	recs     []*seg.Meta
+	requests chan *path_mgmt.SegReq
+}
+
+func (m *testMessenger) GetPathSegs(ctx context.Context, msg *path_mgmt.SegReq, a net.Addr,
+	id uint64) (*path_mgmt.SegReply, error) {
+
+	m.requests <- msg
+	return &path_mgmt.SegReply{Req: msg, Recs: &path_mgmt.SegRecs{Recs: m.recs}}, nil
+}
+
+// testTrustStore returns the same TRC for every ISD. All other calls panic.
+type testTrustStore struct {
+	infra.TrustStore
+	trc *trc.TRC
+}
+
+func (s *testTrustStore) GetValidTRC(ctx context.Context, isd addr.ISD,
+	trail ...addr.ISD) (*trc.TRC, error) {
+
+	return s.trc, nil
+}
+
+// newTestSeg returns a segment over the interfaces ifIDs of g that is valid
+// from now on. Unlike the segments returned by g.Beacon, it contains the raw
+// AS entries required by the path database.
+func newTestSeg(t *testing.T, g *graph.Graph, ifIDs ...common.IFIDType) *seg.PathSegment {
+	t.Helper()
+
+	beacon := g.Beacon(ifIDs)
+	info, err := beacon.InfoF()
+	xtest.FailOnErr(t, err)
+	info.TsInt = uint32(time.Now().Unix())
+	segment, err := seg.NewSeg(info)
+	xtest.FailOnErr(t, err)
+	for _, asEntry := range beacon.ASEntries {
+		err := segment.AddASEntry(asEntry, proto.SignType_none, nil)
+		xtest.FailOnErr(t, err)
+	}
+	return segment
+}
+
+func newTestService(t *testing.T) (*Service, *testMessenger) {
+	t.Helper()
+
+	topo, err := topology.LoadFromFile(xtest.ExpandPath("topology.json"))
+	xtest.FailOnErr(t, err)
+	pathDB, err := pathdb.New("", "mem")
+	xtest.FailOnErr(t, err)
+	g := graph.NewDefaultGraph()
+	msger := &testMessenger{
+		recs: []*seg.Meta{
+			{
+				Type:    proto.PathSegType_up,
+				Segment: *newTestSeg(t, g, 1316, 1619, 1910),
+			},
+			{
+				Type:    proto.PathSegType_down,
+				Segment: *newTestSeg(t, g, 1316),
+			},
+		},
+		requests: make(chan *path_mgmt.SegReq, 10),
+	}
+	trustStore := &testTrustStore{
+		trc: &trc.TRC{
+			CoreASes: map[addr.IA]*trc.CoreAS{
+				xtest.MustParseIA("1-ff00:0:110"): {},
+				xtest.MustParseIA("1-ff00:0:120"): {},
+				xtest.MustParseIA("1-ff00:0:130"): {},
+			},
+		},
+	}
+	revCache := NewRevCache(cache.NoExpiration, time.Second)
+	return NewService(topo, msger, pathDB, trustStore, revCache), msger
+}
+
+// newTestRevocation returns an unsigned revocation of interface ifID of AS ia.
+func newTestRevocation(t *testing.T, ia addr.IA, ifID common.IFIDType) *path_mgmt.SignedRevInfo {
+	t.Helper()
+
+	info := &path_mgmt.RevInfo{
+		IfID:         uint64(ifID),
+		RawIsdas:     ia.IAInt(),
+		LinkType:     proto.LinkType_parent,
+		RawTimestamp: uint32(time.Now().Unix()),
+		RawTTL:       10,
+	}
+	// RevInfo is not a root type, so it cannot be packed with proto.PackRoot.
+	msg, arena, err := capnp.NewMessage(capnp.SingleSegment(nil))
+	xtest.FailOnErr(t, err)
+	root, err := proto.NewRootRevInfo(arena)
+	xtest.FailOnErr(t, err)
+	xtest.FailOnErr(t, pogs.Insert(uint64(info.ProtoId()), root.Struct, info))
+	blob, err := msg.MarshalPacked()
+	xtest.FailOnErr(t, err)
+	return &path_mgmt.SignedRevInfo{
+		Blob: blob,
+		Sign: proto.NewSignS(proto.SignType_none, nil),
+	}
+}
+
+func TestServiceFetchPaths(t *testing.T) {
+	Convey("Paths are built from segments fetched from the path server", t, func() {
+		s, msger := newTestService(t)
+		conn, err := s.Connect()
+		xtest.FailOnErr(t, err)
+		src := xtest.MustParseIA("1-ff00:0:133")
+		dst := xtest.MustParseIA("1-ff00:0:131")
+
+		reply, err := conn.Paths(dst, src, 5, sciond.PathReqFlags{})
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("code", reply.ErrorCode, ShouldEqual, sciond.ErrorOk)
+		SoMsg("entries", len(reply.Entries), ShouldBeGreaterThan, 0)
+		for _, entry := range reply.Entries {
+			ifaces := entry.Path.Interfaces
+			SoMsg("first hop", ifaces[0].IfID, ShouldEqual, 1019)
+			SoMsg("dst", ifaces[len(ifaces)-1].ISD_AS(), ShouldResemble, dst)
+		}
+		req := <-msger.requests
+		SoMsg("req src", req.SrcIA(), ShouldResemble, src)
+		SoMsg("req dst", req.DstIA(), ShouldResemble, dst)
+
+		Convey("Revoked interfaces are excluded from later paths", func() {
+			revReply, err := conn.RevNotification(
+				newTestRevocation(t, xtest.MustParseIA("1-ff00:0:132"), 1910))
+			SoMsg("rev err", err, ShouldBeNil)
+			SoMsg("rev result", revReply.Result, ShouldEqual, sciond.RevValid)
+			reply, err := conn.Paths(dst, src, 5, sciond.PathReqFlags{})
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("code", reply.ErrorCode, ShouldEqual, sciond.ErrorNoPaths)
+
+			revList, err := conn.RevList(addr.IA{}, 0)
+			SoMsg("list err", err, ShouldBeNil)
+			SoMsg("list code", revList.ErrorCode, ShouldEqual, sciond.RevListOk)
+			SoMsg("list entries", len(revList.Entries), ShouldEqual, 1)
+			entry := revList.Entries[0]
+			SoMsg("list ia", entry.ISD_AS(), ShouldResemble, xtest.MustParseIA("1-ff00:0:132"))
+			SoMsg("list ifid", entry.IfID, ShouldEqual, 1910)
+			revList, err = conn.RevList(xtest.MustParseIA("1-ff00:0:132"), time.Hour)
+			SoMsg("list ia err", err, ShouldBeNil)
+			SoMsg("list ia entries", len(revList.Entries), ShouldEqual, 1)
+			revList, err = conn.RevList(xtest.MustParseIA("1-ff00:0:131"), 0)
+			SoMsg("list other ia err", err, ShouldBeNil)
+			SoMsg("list other ia entries", len(revList.Entries), ShouldEqual, 0)
+		})
+		Convey("Malformed revocations are reported as unknown", func() {
+			revReply, err := conn.RevNotification(&path_mgmt.SignedRevInfo{
+				Blob: common.RawBytes{0x01},
+				Sign: proto.NewSignS(proto.SignType_none, nil),
+			})
+			SoMsg("rev err", err, ShouldBeNil)
+			SoMsg("rev result", revReply.Result, ShouldEqual, sciond.RevUnknown)
+		})
+	})
+}
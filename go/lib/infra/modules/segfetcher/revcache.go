@@ -12,14 +12,17 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-package fetcher
+package segfetcher
 
 import (
+	"context"
+	"sort"
 	"sync"
 	"time"
 
 	cache "github.com/patrickmn/go-cache"
 
+	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
 	"github.com/scionproto/scion/go/lib/revcache"
 )
@@ -45,7 +48,7 @@ func (c *RevCache) Get(k *revcache.Key) (*path_mgmt.SignedRevInfo, bool) {
 	if !ok {
 		return nil, false
 	}
-	return obj.(*path_mgmt.SignedRevInfo), true
+	return obj.(*revcache.Entry).Rev, true
 }
 
 func (c *RevCache) Set(k *revcache.Key, rev *path_mgmt.SignedRevInfo, ttl time.Duration) bool {
@@ -54,9 +57,53 @@ func (c *RevCache) Set(k *revcache.Key, rev *path_mgmt.SignedRevInfo, ttl time.D
 	key := k.String()
 	_, exp, ok := c.c.GetWithExpiration(key)
 	// If not yet in cache set, otherwise update expiry if it is later than current one.
-	if !ok || time.Now().Add(ttl).After(exp) {
-		c.c.Set(key, rev, ttl)
+	expiry := time.Now().Add(ttl)
+	if !ok || expiry.After(exp) {
+		c.c.Set(key, &revcache.Entry{Key: *k, Rev: rev, Expiry: expiry}, ttl)
 		return true
 	}
 	return false
 }
+
+func (c *RevCache) GetAll(ctx context.Context, ia addr.IA) ([]*revcache.Entry, error) {
+	entries := c.filter(func(e *revcache.Entry) bool { return e.Key.IA().Eq(ia) })
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key.IfID() < entries[j].Key.IfID()
+	})
+	return entries, nil
+}
+
+func (c *RevCache) GetExpiring(ctx context.Context,
+	deadline time.Time) ([]*revcache.Entry, error) {
+
+	entries := c.filter(func(e *revcache.Entry) bool { return e.Expiry.Before(deadline) })
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Expiry.Before(entries[j].Expiry)
+	})
+	return entries, nil
+}
+
+func (c *RevCache) ForEach(ctx context.Context, f func(*revcache.Entry) bool) error {
+	// Iterate over a snapshot, such that f is not called with the lock held.
+	for _, e := range c.filter(func(*revcache.Entry) bool { return true }) {
+		if !f(e) {
+			break
+		}
+	}
+	return nil
+}
+
+// filter returns all unexpired entries for which cond returns true.
+func (c *RevCache) filter(cond func(*revcache.Entry) bool) []*revcache.Entry {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	var entries []*revcache.Entry
+	// Items only returns unexpired items.
+	for _, item := range c.c.Items() {
+		e := item.Object.(*revcache.Entry)
+		if cond(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
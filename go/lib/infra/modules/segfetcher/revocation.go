@@ -0,0 +1,147 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package segfetcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
+	"github.com/scionproto/scion/go/lib/infra/modules/segverifier"
+	"github.com/scionproto/scion/go/lib/revcache"
+	"github.com/scionproto/scion/go/lib/sciond"
+)
+
+// VerifyRevocation verifies sRevInfo, and classifies the outcome as reported
+// to SCIOND clients in a RevReply. The extracted RevInfo is returned if the
+// revocation is valid; in that case, the returned error is nil.
+func VerifyRevocation(ctx context.Context,
+	sRevInfo *path_mgmt.SignedRevInfo) (*path_mgmt.RevInfo, sciond.RevResult, error) {
+
+	info, err := verifySRevInfo(ctx, sRevInfo)
+	switch {
+	case isValid(err):
+		return info, sciond.RevValid, nil
+	case isStale(err):
+		return nil, sciond.RevStale, err
+	case isInvalid(err):
+		return nil, sciond.RevInvalid, err
+	case isUnknown(err):
+		return nil, sciond.RevUnknown, err
+	default:
+		panic(fmt.Sprintf("unknown error type, err = %v", err))
+	}
+}
+
+// RevList lists the revocations in revCache of interfaces in AS ia that
+// expire within expiringWithin, as reported to SCIOND clients in a
+// RevListReply. Zero values match all revocations. If the revocations cannot
+// be listed, the error code of the reply is set and the error is returned.
+func RevList(ctx context.Context, revCache revcache.RevCache, ia addr.IA,
+	expiringWithin time.Duration) (*sciond.RevListReply, error) {
+
+	entries, err := revListEntries(ctx, revCache, ia, expiringWithin)
+	reply := &sciond.RevListReply{}
+	if err != nil {
+		reply.ErrorCode = sciond.RevListInternal
+	}
+	for _, e := range entries {
+		reply.Entries = append(reply.Entries, sciond.RevListReplyEntry{
+			RawIsdas: e.Key.IA().IAInt(),
+			IfID:     e.Key.IfID(),
+			ExpTime:  uint32(e.Expiry.Unix()),
+			SRevInfo: e.Rev,
+		})
+	}
+	return reply, err
+}
+
+// revListEntries returns the revocations in revCache of interfaces in AS ia
+// that expire within expiringWithin. Zero values match all revocations.
+func revListEntries(ctx context.Context, revCache revcache.RevCache, ia addr.IA,
+	expiringWithin time.Duration) ([]*revcache.Entry, error) {
+
+	deadline := time.Now().Add(expiringWithin)
+	switch {
+	case !ia.IsZero():
+		entries, err := revCache.GetAll(ctx, ia)
+		if err != nil || expiringWithin == 0 {
+			return entries, err
+		}
+		var expiring []*revcache.Entry
+		for _, e := range entries {
+			if e.Expiry.Before(deadline) {
+				expiring = append(expiring, e)
+			}
+		}
+		return expiring, nil
+	case expiringWithin != 0:
+		return revCache.GetExpiring(ctx, deadline)
+	default:
+		var entries []*revcache.Entry
+		err := revCache.ForEach(ctx, func(e *revcache.Entry) bool {
+			entries = append(entries, e)
+			return true
+		})
+		return entries, err
+	}
+}
+
+// verifySRevInfo first checks if the RevInfo can be extracted from sRevInfo,
+// and immediately returns with an error if it cannot. Then, revocation
+// verification is performed and the result is returned.
+func verifySRevInfo(ctx context.Context,
+	sRevInfo *path_mgmt.SignedRevInfo) (*path_mgmt.RevInfo, error) {
+
+	// Error out immediately if RevInfo is bad
+	info, err := sRevInfo.RevInfo()
+	if err != nil {
+		return nil, common.NewBasicError("Unable to extract RevInfo", nil)
+	}
+	// FIXME(scrye): pass in trail here
+	err = segverifier.VerifyRevInfo(ctx, sRevInfo, []addr.ISD{})
+	return info, err
+}
+
+// isValid is a placeholder. It should return true if and only if revocation
+// verification ended with an outcome of valid.
+func isValid(err error) bool {
+	// FIXME(scrye): implement this once we have verification
+	return err == nil
+}
+
+// isStale is a placeholder. It should return true if and only if revocation
+// verification ended with an outcome of stale.
+func isStale(err error) bool {
+	// FIXME(scrye): implement this once we have verification
+	return false
+}
+
+// isInvalid is a placeholder. It should return true if and only if revocation
+// verification ended with an outcome of invalid.
+func isInvalid(err error) bool {
+	// FIXME(scrye): implement this once we have verification
+	return false
+}
+
+// isUnknown is a placeholder. It should return true if and only if revocation
+// verification ended with an outcome of unknown.
+func isUnknown(err error) bool {
+	// FIXME(scrye): implement this once we have verification
+	return err != nil
+}
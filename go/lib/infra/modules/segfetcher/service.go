@@ -0,0 +1,198 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package segfetcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
+	"github.com/scionproto/scion/go/lib/infra"
+	"github.com/scionproto/scion/go/lib/infra/disp"
+	"github.com/scionproto/scion/go/lib/infra/messenger"
+	"github.com/scionproto/scion/go/lib/infra/transport"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/pathdb"
+	"github.com/scionproto/scion/go/lib/revcache"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/snet"
+	"github.com/scionproto/scion/go/lib/topology"
+	"github.com/scionproto/scion/go/proto"
+)
+
+const (
+	// DefaultRequestTimeout is the maximum time a request to a Service
+	// connector takes.
+	DefaultRequestTimeout = 10 * time.Second
+	// DefaultEarlyReply is the time after which a path request is answered if
+	// any paths have been found, even if segment fetching is still ongoing.
+	DefaultEarlyReply = 200 * time.Millisecond
+)
+
+var _ sciond.Service = (*Service)(nil)
+
+// Service is an in-process replacement for SCIOND. Path requests are served
+// by a Fetcher, which gets path segments directly from the path servers of the
+// local AS, verifies them and combines them into paths.
+//
+// Connectors returned by Service only support path requests and revocation
+// notifications, which is all a path resolver needs; the other requests
+// return an error.
+type Service struct {
+	fetcher  *Fetcher
+	revCache revcache.RevCache
+}
+
+// NewService returns a new in-process SCIOND service. The arguments are the
+// same as for NewFetcher. Messenger must be able to reach the path servers in
+// topo; see NewMessenger.
+func NewService(topo *topology.Topo, messenger infra.Messenger, pathDB *pathdb.DB,
+	trustStore infra.TrustStore, revCache revcache.RevCache) *Service {
+
+	return &Service{
+		fetcher:  NewFetcher(topo, messenger, pathDB, trustStore, revCache),
+		revCache: revCache,
+	}
+}
+
+// NewMessenger returns a messenger for NewService that listens on local. The
+// messenger uses its own SCION network without path resolver, which suffices
+// to reach the path servers of the local AS. Thus, the path resolver of the
+// application's network can itself be backed by the service, e.g.:
+//   msger, err := segfetcher.NewMessenger(local, "", trustStore, log.Root())
+//   ...
+//   srvc := segfetcher.NewService(topo, msger, pathDB, trustStore, revCache)
+//   pr, err := pathmgr.New(srvc, &pathmgr.Timers{}, log.Root())
+//   ...
+//   network := snet.NewNetworkWithPR(topo.ISD_AS, "", pr)
+// The returned messenger is also set as the messenger of trustStore.
+func NewMessenger(local *snet.Addr, dispatcherPath string, trustStore infra.TrustStore,
+	logger log.Logger) (infra.Messenger, error) {
+
+	network := snet.NewNetworkWithPR(local.IA, dispatcherPath, nil)
+	conn, err := network.ListenSCIONWithBindSVC("udp4", local, nil, addr.SvcNone)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to listen on SCION", err, "addr", local)
+	}
+	msger := messenger.New(
+		disp.New(transport.NewPacketTransport(conn), messenger.DefaultAdapter, logger),
+		trustStore,
+		logger,
+		nil,
+	)
+	trustStore.SetMessenger(msger)
+	return msger, nil
+}
+
+// Connect returns a connector to the service. It never fails.
+func (s *Service) Connect() (sciond.Connector, error) {
+	return &connector{service: s}, nil
+}
+
+// ConnectTimeout is the same as Connect, as there is no connection to
+// establish.
+func (s *Service) ConnectTimeout(timeout time.Duration) (sciond.Connector, error) {
+	return s.Connect()
+}
+
+var _ sciond.Connector = (*connector)(nil)
+
+// connector is a sciond.Connector that directly calls into a Service.
+type connector struct {
+	service *Service
+}
+
+// Paths requests paths from the fetcher. Like a SCIOND server, it reports
+// failures via the error code of the reply, so the returned error is always
+// nil.
+func (c *connector) Paths(dst, src addr.IA, max uint16,
+	f sciond.PathReqFlags) (*sciond.PathReply, error) {
+
+	ctx, cancelF := context.WithTimeout(context.Background(), DefaultRequestTimeout)
+	defer cancelF()
+	req := &sciond.PathReq{
+		Dst:      dst.IAInt(),
+		Src:      src.IAInt(),
+		MaxPaths: max,
+		Flags:    f,
+	}
+	reply, err := c.service.fetcher.GetPaths(ctx, req, DefaultEarlyReply)
+	if err != nil {
+		log.Warn("Unable to get paths", "src", src, "dst", dst, "err", err)
+	}
+	return reply, nil
+}
+
+// ASInfo is not supported.
+func (c *connector) ASInfo(ia addr.IA) (*sciond.ASInfoReply, error) {
+	return nil, common.NewBasicError("ASInfo not supported without SCIOND", nil)
+}
+
+// IFInfo is not supported.
+func (c *connector) IFInfo(ifs []common.IFIDType) (*sciond.IFInfoReply, error) {
+	return nil, common.NewBasicError("IFInfo not supported without SCIOND", nil)
+}
+
+// SVCInfo is not supported.
+func (c *connector) SVCInfo(svcTypes []proto.ServiceType) (*sciond.ServiceInfoReply, error) {
+	return nil, common.NewBasicError("SVCInfo not supported without SCIOND", nil)
+}
+
+func (c *connector) RevNotificationFromRaw(b []byte) (*sciond.RevReply, error) {
+	sRevInfo, err := path_mgmt.NewSignedRevInfoFromRaw(b)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to parse signed revocation info", err)
+	}
+	return c.RevNotification(sRevInfo)
+}
+
+// RevNotification verifies sRevInfo like SCIOND does (see VerifyRevocation)
+// and, if it is valid, adds it to the revocation cache. Paths containing the
+// revoked interface are not returned by future path requests.
+func (c *connector) RevNotification(
+	sRevInfo *path_mgmt.SignedRevInfo) (*sciond.RevReply, error) {
+
+	ctx, cancelF := context.WithTimeout(context.Background(), DefaultRequestTimeout)
+	defer cancelF()
+	info, result, err := VerifyRevocation(ctx, sRevInfo)
+	if err != nil {
+		log.Info("Revocation verification failed", "revocation", sRevInfo, "err", err)
+		return &sciond.RevReply{Result: result}, nil
+	}
+	c.service.revCache.Set(revcache.NewKey(info.IA(), common.IFIDType(info.IfID)),
+		sRevInfo, info.TTL())
+	return &sciond.RevReply{Result: result}, nil
+}
+
+// RevList lists the revocations in the revocation cache like SCIOND does (see
+// RevList).
+func (c *connector) RevList(ia addr.IA,
+	expiringWithin time.Duration) (*sciond.RevListReply, error) {
+
+	ctx, cancelF := context.WithTimeout(context.Background(), DefaultRequestTimeout)
+	defer cancelF()
+	reply, err := RevList(ctx, c.service.revCache, ia, expiringWithin)
+	if err != nil {
+		log.Error("Unable to list revocations", "err", err)
+	}
+	return reply, nil
+}
+
+// Close is a no-op.
+func (c *connector) Close() error {
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package segfetcher
+
+import (
+	"testing"
+	"time"
+
+	cache "github.com/patrickmn/go-cache"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/topology"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func TestServicePaths(t *testing.T) {
+	Convey("Paths requests that need no segments are answered locally", t, func() {
+		topo := topology.NewTopo()
+		topo.ISD_AS = xtest.MustParseIA("1-ff00:0:133")
+		topo.MTU = 1400
+		s := NewService(topo, nil, nil, nil, NewRevCache(cache.NoExpiration, time.Second))
+		conn, err := s.Connect()
+		xtest.FailOnErr(t, err)
+
+		Convey("Destination is the local AS", func() {
+			reply, err := conn.Paths(topo.ISD_AS, addr.IA{}, 5, sciond.PathReqFlags{})
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("code", reply.ErrorCode, ShouldEqual, sciond.ErrorOk)
+			SoMsg("entries", len(reply.Entries), ShouldEqual, 1)
+			SoMsg("mtu", reply.Entries[0].Path.Mtu, ShouldEqual, 1400)
+		})
+		Convey("Source is not the local AS", func() {
+			reply, err := conn.Paths(xtest.MustParseIA("1-ff00:0:131"),
+				xtest.MustParseIA("1-ff00:0:132"), 5, sciond.PathReqFlags{})
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("code", reply.ErrorCode, ShouldEqual, sciond.ErrorBadSrcIA)
+			SoMsg("entries", reply.Entries, ShouldBeEmpty)
+		})
+		Convey("Unsupported requests return an error", func() {
+			_, err := conn.ASInfo(topo.ISD_AS)
+			SoMsg("asinfo", err, ShouldNotBeNil)
+		})
+		Convey("Revocations are listed from the revocation cache", func() {
+			reply, err := conn.RevList(addr.IA{}, 0)
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("code", reply.ErrorCode, ShouldEqual, sciond.RevListOk)
+			SoMsg("entries", reply.Entries, ShouldBeEmpty)
+		})
+	})
+}
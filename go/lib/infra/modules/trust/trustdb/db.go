@@ -139,6 +139,10 @@ const (
 		`
 )
 
+// Migrations contains the upgrade steps from older schema versions to
+// SchemaVersion.
+var Migrations []sqlite.Migration
+
 // DB is a database containing Certificates, Chains and TRCs, stored in JSON format.
 //
 // On errors, GetXxx methods return nil and the error. If no error occurred,
@@ -164,7 +168,7 @@ type DB struct {
 func New(path string) (*DB, error) {
 	var err error
 	db := &DB{}
-	if db.db, err = sqlite.New(path, Schema, SchemaVersion); err != nil {
+	if db.db, err = sqlite.New(path, Schema, SchemaVersion, Migrations...); err != nil {
 		return nil, err
 	}
 	// On future errors, close the sql database before exiting
@@ -19,6 +19,7 @@ package conn
 
 import (
 	"context"
+	"time"
 
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/ctrl/seg"
@@ -38,6 +39,9 @@ type Conn interface {
 	// Deletes all path segments that contain a given interface. Returns the number
 	// of path segments deleted.
 	DeleteWithIntf(context.Context, query.IntfSpec) (int, error)
+	// Deletes all path segments that expired before the given time. Returns
+	// the deleted path segments.
+	DeleteExpired(context.Context, time.Time) ([]*seg.PathSegment, error)
 	// Get returns all path segment(s) matching the parameters specified.
 	Get(context.Context, *query.Params) ([]*query.Result, error)
 }
@@ -0,0 +1,348 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file contains an in-memory backend for the PathDB.
+
+package mem
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/seg"
+	"github.com/scionproto/scion/go/lib/pathdb/conn"
+	"github.com/scionproto/scion/go/lib/pathdb/query"
+	"github.com/scionproto/scion/go/proto"
+)
+
+type segEntry struct {
+	rowID       int64
+	segID       common.RawBytes
+	lastUpdated time.Time
+	expiry      time.Time
+	minExpiry   time.Time
+	seg         *seg.PathSegment
+	types       []proto.PathSegType
+	hpCfgIDs    []*query.HPCfgID
+	intfs       []query.IntfSpec
+	startsAt    addr.IA
+	endsAt      addr.IA
+}
+
+var _ conn.Conn = (*Backend)(nil)
+
+// Backend is a PathDB backend that keeps all path segments in memory. The
+// stored segments are returned as is by Get, so callers must not modify them.
+type Backend struct {
+	sync.RWMutex
+	// segs maps the string representation of segment IDs to the entries.
+	segs      map[string]*segEntry
+	nextRowID int64
+}
+
+// New returns a new, empty in-memory backend.
+func New() *Backend {
+	return &Backend{
+		segs:      make(map[string]*segEntry),
+		nextRowID: 1,
+	}
+}
+
+func (b *Backend) Insert(ctx context.Context, pseg *seg.PathSegment,
+	segTypes []proto.PathSegType) (int, error) {
+
+	return b.InsertWithHPCfgIDs(ctx, pseg, segTypes, []*query.HPCfgID{&query.NullHpCfgID})
+}
+
+func (b *Backend) InsertWithHPCfgIDs(ctx context.Context, pseg *seg.PathSegment,
+	segTypes []proto.PathSegType, hpCfgIDs []*query.HPCfgID) (int, error) {
+
+	b.Lock()
+	defer b.Unlock()
+	segID, err := pseg.ID()
+	if err != nil {
+		return 0, err
+	}
+	expiry, err := pseg.MaxExpiry()
+	if err != nil {
+		return 0, err
+	}
+	minExpiry, err := pseg.MinExpiry()
+	if err != nil {
+		return 0, err
+	}
+	// Check if we already have a path segment.
+	if entry, ok := b.segs[string(segID)]; ok {
+		// Check if the new segment is more recent.
+		newInfo, _ := pseg.InfoF()
+		curInfo, _ := entry.seg.InfoF()
+		if !newInfo.Timestamp().After(curInfo.Timestamp()) {
+			return 0, nil
+		}
+		// Update existing path segment.
+		entry.seg = pseg
+		entry.lastUpdated = now()
+		entry.expiry = expiry
+		entry.minExpiry = minExpiry
+		entry.addTypes(segTypes)
+		entry.addHPCfgIDs(hpCfgIDs)
+		return 1, nil
+	}
+	// Do full insert.
+	intfs, err := extractIntfs(pseg.ASEntries)
+	if err != nil {
+		return 0, err
+	}
+	entry := &segEntry{
+		rowID:       b.nextRowID,
+		segID:       segID,
+		lastUpdated: now(),
+		expiry:      expiry,
+		minExpiry:   minExpiry,
+		seg:         pseg,
+		intfs:       intfs,
+		startsAt:    pseg.ASEntries[0].IA(),
+		endsAt:      pseg.ASEntries[pseg.MaxAEIdx()].IA(),
+	}
+	entry.addTypes(segTypes)
+	entry.addHPCfgIDs(hpCfgIDs)
+	b.segs[string(segID)] = entry
+	b.nextRowID++
+	return 1, nil
+}
+
+// now returns the current time with the resolution of the SQLite backend, so
+// that both backends order results by freshness in the same way.
+func now() time.Time {
+	return time.Unix(time.Now().Unix(), 0)
+}
+
+// extractIntfs returns the interfaces of a segment the same way the SQLite
+// backend indexes them: the ingress interface of every hop entry and the
+// egress interface of the first hop entry in each AS entry.
+func extractIntfs(ases []*seg.ASEntry) ([]query.IntfSpec, error) {
+	var intfs []query.IntfSpec
+	for _, as := range ases {
+		ia := as.IA()
+		for idx, hop := range as.HopEntries {
+			hof, err := hop.HopField()
+			if err != nil {
+				return nil, common.NewBasicError("Failed to extract hop field", err)
+			}
+			if hof.ConsIngress != 0 {
+				intfs = append(intfs, query.IntfSpec{IA: ia, IfID: uint64(hof.ConsIngress)})
+			}
+			if idx == 0 && hof.ConsEgress != 0 {
+				intfs = append(intfs, query.IntfSpec{IA: ia, IfID: uint64(hof.ConsEgress)})
+			}
+		}
+	}
+	return intfs, nil
+}
+
+func (e *segEntry) addTypes(segTypes []proto.PathSegType) {
+Outer:
+	for _, segType := range segTypes {
+		for _, t := range e.types {
+			if t == segType {
+				continue Outer
+			}
+		}
+		e.types = append(e.types, segType)
+	}
+}
+
+func (e *segEntry) addHPCfgIDs(hpCfgIDs []*query.HPCfgID) {
+Outer:
+	for _, hpCfgID := range hpCfgIDs {
+		for _, h := range e.hpCfgIDs {
+			if h.Eq(hpCfgID) {
+				continue Outer
+			}
+		}
+		e.hpCfgIDs = append(e.hpCfgIDs, &query.HPCfgID{IA: hpCfgID.IA, ID: hpCfgID.ID})
+	}
+}
+
+func (b *Backend) Delete(ctx context.Context, segID common.RawBytes) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	if _, ok := b.segs[string(segID)]; !ok {
+		return 0, nil
+	}
+	delete(b.segs, string(segID))
+	return 1, nil
+}
+
+func (b *Backend) DeleteWithIntf(ctx context.Context, intf query.IntfSpec) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	return len(b.deleteIf(func(e *segEntry) bool {
+		return e.hasIntf(&intf)
+	})), nil
+}
+
+func (b *Backend) DeleteExpired(ctx context.Context,
+	now time.Time) ([]*seg.PathSegment, error) {
+
+	b.Lock()
+	defer b.Unlock()
+	return b.deleteIf(func(e *segEntry) bool {
+		return e.expiry.Before(now)
+	}), nil
+}
+
+// deleteIf removes all entries for which cond returns true and returns the
+// segments of the removed entries. The caller must hold the write lock.
+func (b *Backend) deleteIf(cond func(*segEntry) bool) []*seg.PathSegment {
+	var deleted []*seg.PathSegment
+	for k, e := range b.segs {
+		if cond(e) {
+			delete(b.segs, k)
+			deleted = append(deleted, e.seg)
+		}
+	}
+	return deleted
+}
+
+func (b *Backend) Get(ctx context.Context, params *query.Params) ([]*query.Result, error) {
+	b.RLock()
+	defer b.RUnlock()
+	var entries []*segEntry
+	for _, e := range b.segs {
+		if e.matches(params) {
+			entries = append(entries, e)
+		}
+	}
+	if params != nil && params.Limit > 0 {
+		// Return the freshest segments first.
+		sort.Slice(entries, func(i, j int) bool {
+			if !entries[i].lastUpdated.Equal(entries[j].lastUpdated) {
+				return entries[i].lastUpdated.After(entries[j].lastUpdated)
+			}
+			return entries[i].rowID > entries[j].rowID
+		})
+		if len(entries) > params.Limit {
+			entries = entries[:params.Limit]
+		}
+	} else {
+		// Return the results in insertion order, like the SQLite backend does.
+		sort.Slice(entries, func(i, j int) bool { return entries[i].rowID < entries[j].rowID })
+	}
+	res := []*query.Result{}
+	for _, e := range entries {
+		r := &query.Result{Seg: e.seg}
+		for _, hpCfgID := range e.hpCfgIDs {
+			if params == nil || len(params.HpCfgIDs) == 0 || hpCfgIDIn(hpCfgID, params.HpCfgIDs) {
+				r.HpCfgIDs = append(r.HpCfgIDs, &query.HPCfgID{IA: hpCfgID.IA, ID: hpCfgID.ID})
+			}
+		}
+		res = append(res, r)
+	}
+	return res, nil
+}
+
+// matches returns whether the entry satisfies all constraints in params.
+// Within a single constraint, it is sufficient if one of the values matches.
+func (e *segEntry) matches(params *query.Params) bool {
+	if params == nil {
+		return true
+	}
+	if len(params.SegID) > 0 && !bytes.Equal(params.SegID, e.segID) {
+		return false
+	}
+	if len(params.SegTypes) > 0 && !e.hasAnyType(params.SegTypes) {
+		return false
+	}
+	if len(params.HpCfgIDs) > 0 && !e.hasAnyHPCfgID(params.HpCfgIDs) {
+		return false
+	}
+	if len(params.Intfs) > 0 && !e.hasAnyIntf(params.Intfs) {
+		return false
+	}
+	if len(params.StartsAt) > 0 && !iaIn(e.startsAt, params.StartsAt) {
+		return false
+	}
+	if len(params.EndsAt) > 0 && !iaIn(e.endsAt, params.EndsAt) {
+		return false
+	}
+	if !params.MinExpiry.IsZero() && e.minExpiry.Unix() < params.MinExpiry.Unix() {
+		return false
+	}
+	if !params.MinLastUpdate.IsZero() && e.lastUpdated.Unix() < params.MinLastUpdate.Unix() {
+		return false
+	}
+	return true
+}
+
+func (e *segEntry) hasAnyType(segTypes []proto.PathSegType) bool {
+	for _, segType := range segTypes {
+		for _, t := range e.types {
+			if t == segType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (e *segEntry) hasAnyHPCfgID(hpCfgIDs []*query.HPCfgID) bool {
+	for _, hpCfgID := range e.hpCfgIDs {
+		if hpCfgIDIn(hpCfgID, hpCfgIDs) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *segEntry) hasAnyIntf(intfs []*query.IntfSpec) bool {
+	for _, intf := range intfs {
+		if e.hasIntf(intf) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *segEntry) hasIntf(intf *query.IntfSpec) bool {
+	for _, i := range e.intfs {
+		if i.IA.Eq(intf.IA) && i.IfID == intf.IfID {
+			return true
+		}
+	}
+	return false
+}
+
+func hpCfgIDIn(hpCfgID *query.HPCfgID, hpCfgIDs []*query.HPCfgID) bool {
+	for _, h := range hpCfgIDs {
+		if hpCfgID.Eq(h) {
+			return true
+		}
+	}
+	return false
+}
+
+func iaIn(ia addr.IA, ias []addr.IA) bool {
+	for _, other := range ias {
+		if ia.Eq(other) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file contains the change notification support of the PathDB frontend.
+
+package pathdb
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/ctrl/seg"
+)
+
+// EventType describes how a path segment in the DB changed.
+type EventType int
+
+const (
+	// EventInsert signals that a new path segment was inserted.
+	EventInsert EventType = iota
+	// EventUpdate signals that a stored path segment was replaced by a more
+	// recent version.
+	EventUpdate
+	// EventDelete signals that a path segment was removed.
+	EventDelete
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventInsert:
+		return "Insert"
+	case EventUpdate:
+		return "Update"
+	case EventDelete:
+		return "Delete"
+	}
+	return fmt.Sprintf("UNKNOWN (%d)", int(t))
+}
+
+// Event is a change to a single path segment in the DB.
+type Event struct {
+	Type EventType
+	// Seg is the inserted or updated segment, or the segment that was deleted.
+	Seg *seg.PathSegment
+}
+
+// Filter selects the path segments a subscription is interested in. A path
+// segment matches if it starts at one of the ASes in StartsAt and ends at one
+// of the ASes in EndsAt. An empty list matches any AS.
+type Filter struct {
+	StartsAt []addr.IA
+	EndsAt   []addr.IA
+}
+
+func (f *Filter) matches(pseg *seg.PathSegment) bool {
+	if len(pseg.ASEntries) == 0 {
+		return false
+	}
+	return iaIn(pseg.ASEntries[0].IA(), f.StartsAt) &&
+		iaIn(pseg.ASEntries[pseg.MaxAEIdx()].IA(), f.EndsAt)
+}
+
+func iaIn(ia addr.IA, ias []addr.IA) bool {
+	if len(ias) == 0 {
+		return true
+	}
+	for _, other := range ias {
+		if ia.Eq(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription delivers the events of path segments matching a filter. Events
+// are delivered without blocking writers to the DB; if the subscriber does
+// not keep up and the buffer is full, events are dropped and counted.
+type Subscription struct {
+	db      *DB
+	filter  Filter
+	events  chan *Event
+	dropped uint64
+}
+
+// Subscribe registers a new subscription for changes to path segments that
+// match filter. bufSize is the number of events that are buffered for the
+// subscriber. Close must be called once the subscription is no longer needed.
+func (db *DB) Subscribe(filter Filter, bufSize int) *Subscription {
+	s := &Subscription{
+		db:     db,
+		filter: filter,
+		events: make(chan *Event, bufSize),
+	}
+	db.subsLock.Lock()
+	defer db.subsLock.Unlock()
+	db.subs[s] = struct{}{}
+	return s
+}
+
+// Events returns the channel on which events are delivered. The channel is
+// closed when the subscription is closed.
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+// Dropped returns the number of events that were dropped because the buffer
+// was full.
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close removes the subscription from the DB and closes the events channel.
+// Close must be called at most once.
+func (s *Subscription) Close() {
+	s.db.subsLock.Lock()
+	defer s.db.subsLock.Unlock()
+	delete(s.db.subs, s)
+	close(s.events)
+}
+
+func (s *Subscription) deliver(e *Event) {
+	if !s.filter.matches(e.Seg) {
+		return
+	}
+	select {
+	case s.events <- e:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// hasSubscribers returns whether any subscription is registered. It is used
+// to skip the bookkeeping needed for events if nobody is listening.
+func (db *DB) hasSubscribers() bool {
+	db.subsLock.RLock()
+	defer db.subsLock.RUnlock()
+	return len(db.subs) > 0
+}
+
+func (db *DB) notify(events []*Event) {
+	db.subsLock.RLock()
+	defer db.subsLock.RUnlock()
+	for s := range db.subs {
+		for _, e := range events {
+			s.deliver(e)
+		}
+	}
+}
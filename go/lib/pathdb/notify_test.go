@@ -0,0 +1,139 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pathdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/pathdb/pathdbtest"
+	"github.com/scionproto/scion/go/lib/pathdb/query"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/proto"
+)
+
+var (
+	ia330 = addr.IA{I: 1, A: 0xff0000000330}
+	ia331 = addr.IA{I: 1, A: 0xff0000000331}
+	ia332 = addr.IA{I: 1, A: 0xff0000000332}
+
+	ifs1 = []uint64{0, 5, 2, 3, 6, 3, 1, 0}
+	ifs2 = []uint64{0, 4, 2, 3, 1, 3, 2, 0}
+
+	types = []proto.PathSegType{proto.PathSegType_down}
+)
+
+func setupDB(t *testing.T) *DB {
+	db, err := New("", "mem")
+	xtest.FailOnErr(t, err)
+	return db
+}
+
+func expectEvents(sub *Subscription, expected ...EventType) {
+	for _, eType := range expected {
+		select {
+		case e := <-sub.Events():
+			SoMsg("Event type", e.Type, ShouldEqual, eType)
+		default:
+			SoMsg("Event missing", eType, ShouldBeNil)
+		}
+	}
+	select {
+	case e := <-sub.Events():
+		SoMsg("Unexpected event", e, ShouldBeNil)
+	default:
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	ctx, cancelF := context.WithTimeout(context.Background(), time.Second)
+	defer cancelF()
+	Convey("Subscribers should be notified about inserts, updates and deletes", t, func() {
+		db := setupDB(t)
+		sub := db.Subscribe(Filter{EndsAt: []addr.IA{ia332}}, 10)
+		defer sub.Close()
+		oldSeg, segID := pathdbtest.AllocPathSegment(ifs1, 10)
+		newSeg, _ := pathdbtest.AllocPathSegment(ifs1, 20)
+		_, err := db.Insert(ctx, oldSeg, types)
+		xtest.FailOnErr(t, err)
+		_, err = db.Insert(ctx, newSeg, types)
+		xtest.FailOnErr(t, err)
+		// Older segments are ignored and do not trigger an event.
+		_, err = db.Insert(ctx, oldSeg, types)
+		xtest.FailOnErr(t, err)
+		_, err = db.Delete(ctx, segID)
+		xtest.FailOnErr(t, err)
+		expectEvents(sub, EventInsert, EventUpdate, EventDelete)
+	})
+	Convey("Subscribers should only receive events matching their filter", t, func() {
+		db := setupDB(t)
+		subMatch := db.Subscribe(Filter{StartsAt: []addr.IA{ia330}, EndsAt: []addr.IA{ia332}}, 10)
+		defer subMatch.Close()
+		subOther := db.Subscribe(Filter{EndsAt: []addr.IA{ia331}}, 10)
+		defer subOther.Close()
+		pseg, _ := pathdbtest.AllocPathSegment(ifs1, 10)
+		_, err := db.Insert(ctx, pseg, types)
+		xtest.FailOnErr(t, err)
+		expectEvents(subMatch, EventInsert)
+		expectEvents(subOther)
+	})
+	Convey("Bulk deletes should notify about every deleted segment", t, func() {
+		db := setupDB(t)
+		sub := db.Subscribe(Filter{}, 10)
+		defer sub.Close()
+		pseg1, _ := pathdbtest.AllocPathSegment(ifs1, 10)
+		pseg2, _ := pathdbtest.AllocPathSegment(ifs2, uint32(time.Now().Unix()))
+		_, err := db.Insert(ctx, pseg1, types)
+		xtest.FailOnErr(t, err)
+		_, err = db.Insert(ctx, pseg2, types)
+		xtest.FailOnErr(t, err)
+		expectEvents(sub, EventInsert, EventInsert)
+		deleted, err := db.DeleteExpired(ctx, time.Now())
+		xtest.FailOnErr(t, err)
+		SoMsg("Deleted expired", deleted, ShouldEqual, 1)
+		expectEvents(sub, EventDelete)
+		deleted, err = db.DeleteWithIntf(ctx, query.IntfSpec{IA: ia331, IfID: 2})
+		xtest.FailOnErr(t, err)
+		SoMsg("Deleted with intf", deleted, ShouldEqual, 1)
+		expectEvents(sub, EventDelete)
+	})
+	Convey("Events should be dropped if the subscriber does not keep up", t, func() {
+		db := setupDB(t)
+		sub := db.Subscribe(Filter{}, 1)
+		defer sub.Close()
+		pseg1, _ := pathdbtest.AllocPathSegment(ifs1, 10)
+		pseg2, _ := pathdbtest.AllocPathSegment(ifs2, 10)
+		_, err := db.Insert(ctx, pseg1, types)
+		xtest.FailOnErr(t, err)
+		_, err = db.Insert(ctx, pseg2, types)
+		xtest.FailOnErr(t, err)
+		expectEvents(sub, EventInsert)
+		SoMsg("Dropped", sub.Dropped(), ShouldEqual, 1)
+	})
+	Convey("Closing a subscription should close the events channel", t, func() {
+		db := setupDB(t)
+		sub := db.Subscribe(Filter{}, 1)
+		sub.Close()
+		_, ok := <-sub.Events()
+		SoMsg("Closed", ok, ShouldBeFalse)
+		pseg, _ := pathdbtest.AllocPathSegment(ifs1, 10)
+		_, err := db.Insert(ctx, pseg, types)
+		xtest.FailOnErr(t, err)
+	})
+}
@@ -19,10 +19,13 @@ package pathdb
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/ctrl/seg"
 	"github.com/scionproto/scion/go/lib/pathdb/conn"
+	"github.com/scionproto/scion/go/lib/pathdb/mem"
 	"github.com/scionproto/scion/go/lib/pathdb/query"
 	"github.com/scionproto/scion/go/lib/pathdb/sqlite"
 	"github.com/scionproto/scion/go/proto"
@@ -30,16 +33,24 @@ import (
 
 type DB struct {
 	conn conn.Conn
+	// writeLock serializes modifications, such that the events delivered to
+	// subscribers reflect the actual changes.
+	writeLock sync.Mutex
+	subsLock  sync.RWMutex
+	subs      map[*Subscription]struct{}
 }
 
 // New creates a new or open an existing PathDB at a given path using the
-// given backend.
+// given backend. Supported backends are "sqlite" and "mem". The path is
+// ignored by the in-memory backend.
 func New(path string, backend string) (*DB, error) {
-	db := &DB{}
+	db := &DB{subs: make(map[*Subscription]struct{})}
 	var err error
 	switch backend {
 	case "sqlite":
 		db.conn, err = sqlite.New(path)
+	case "mem":
+		db.conn = mem.New()
 	default:
 		return nil, common.NewBasicError("Unknown backend", nil, "backend", backend)
 	}
@@ -54,7 +65,9 @@ func New(path string, backend string) (*DB, error) {
 func (db *DB) Insert(ctx context.Context, pseg *seg.PathSegment,
 	segTypes []proto.PathSegType) (int, error) {
 
-	return db.conn.Insert(ctx, pseg, segTypes)
+	return db.insert(ctx, pseg, func() (int, error) {
+		return db.conn.Insert(ctx, pseg, segTypes)
+	})
 }
 
 // InsertWithHPCfgIDs inserts or updates a path segment with a set of HPCfgIDs. It
@@ -62,19 +75,100 @@ func (db *DB) Insert(ctx context.Context, pseg *seg.PathSegment,
 func (db *DB) InsertWithHPCfgIDs(ctx context.Context, pseg *seg.PathSegment,
 	segTypes []proto.PathSegType, hpCfgIDs []*query.HPCfgID) (int, error) {
 
-	return db.conn.InsertWithHPCfgIDs(ctx, pseg, segTypes, hpCfgIDs)
+	return db.insert(ctx, pseg, func() (int, error) {
+		return db.conn.InsertWithHPCfgIDs(ctx, pseg, segTypes, hpCfgIDs)
+	})
+}
+
+func (db *DB) insert(ctx context.Context, pseg *seg.PathSegment,
+	insertF func() (int, error)) (int, error) {
+
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+	if !db.hasSubscribers() {
+		return insertF()
+	}
+	segID, err := pseg.ID()
+	if err != nil {
+		return 0, err
+	}
+	existing, err := db.conn.Get(ctx, &query.Params{SegID: segID})
+	if err != nil {
+		return 0, err
+	}
+	inserted, err := insertF()
+	if err != nil || inserted == 0 {
+		return inserted, err
+	}
+	event := &Event{Type: EventInsert, Seg: pseg}
+	if len(existing) > 0 {
+		event.Type = EventUpdate
+	}
+	db.notify([]*Event{event})
+	return inserted, nil
 }
 
 // Delete deletes a path segment with a given ID. Returns the number of deleted
 // path segments (0 or 1).
 func (db *DB) Delete(ctx context.Context, segID common.RawBytes) (int, error) {
-	return db.conn.Delete(ctx, segID)
+	return db.delete(ctx, &query.Params{SegID: segID}, func() (int, error) {
+		return db.conn.Delete(ctx, segID)
+	})
 }
 
 // DeleteWithIntf deletes all path segments that contain a given interface. Returns
 // the number of path segments deleted.
 func (db *DB) DeleteWithIntf(ctx context.Context, intf query.IntfSpec) (int, error) {
-	return db.conn.DeleteWithIntf(ctx, intf)
+	params := &query.Params{Intfs: []*query.IntfSpec{&intf}}
+	return db.delete(ctx, params, func() (int, error) {
+		return db.conn.DeleteWithIntf(ctx, intf)
+	})
+}
+
+// DeleteExpired deletes all path segments that expired before now. Returns
+// the number of path segments deleted.
+func (db *DB) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+	segs, err := db.conn.DeleteExpired(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	if len(segs) > 0 && db.hasSubscribers() {
+		events := make([]*Event, 0, len(segs))
+		for _, pseg := range segs {
+			events = append(events, &Event{Type: EventDelete, Seg: pseg})
+		}
+		db.notify(events)
+	}
+	return len(segs), nil
+}
+
+// delete calls deleteF and notifies the subscribers about the deleted
+// segments. params must select exactly the segments removed by deleteF. This
+// holds because all modifications are serialized by writeLock.
+func (db *DB) delete(ctx context.Context, params *query.Params,
+	deleteF func() (int, error)) (int, error) {
+
+	db.writeLock.Lock()
+	defer db.writeLock.Unlock()
+	if !db.hasSubscribers() {
+		return deleteF()
+	}
+	affected, err := db.conn.Get(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := deleteF()
+	if err != nil || deleted == 0 {
+		return deleted, err
+	}
+	events := make([]*Event, 0, len(affected))
+	for _, r := range affected {
+		events = append(events, &Event{Type: EventDelete, Seg: r.Seg})
+	}
+	db.notify(events)
+	return deleted, nil
 }
 
 // Get returns all path segment(s) matching the parameters specified.
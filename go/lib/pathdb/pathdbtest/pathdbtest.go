@@ -0,0 +1,500 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pathdbtest contains a conformance test suite that every PathDB
+// backend has to pass.
+package pathdbtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/seg"
+	"github.com/scionproto/scion/go/lib/pathdb/conn"
+	"github.com/scionproto/scion/go/lib/pathdb/query"
+	"github.com/scionproto/scion/go/lib/spath"
+	"github.com/scionproto/scion/go/proto"
+)
+
+var (
+	ia330 = addr.IA{I: 1, A: 0xff0000000330}
+	ia311 = addr.IA{I: 1, A: 0xff0000000311}
+	ia331 = addr.IA{I: 1, A: 0xff0000000331}
+	ia332 = addr.IA{I: 1, A: 0xff0000000332}
+
+	ifs1 = []uint64{0, 5, 2, 3, 6, 3, 1, 0}
+	ifs2 = []uint64{0, 4, 2, 3, 1, 3, 2, 0}
+	ifs3 = []uint64{0, 7, 2, 3, 6, 3, 4, 0}
+
+	hpCfgIDs = []*query.HPCfgID{
+		&query.NullHpCfgID,
+		{IA: ia330, ID: 0xdeadbeef},
+	}
+	types = []proto.PathSegType{proto.PathSegType_up, proto.PathSegType_down}
+
+	timeout = time.Second
+)
+
+// TestPathDB runs the conformance test suite against a backend. setup is
+// called before each test and must return a new, empty backend. cleanup is
+// called with that backend after each test.
+func TestPathDB(t *testing.T, setup func() conn.Conn, cleanup func(conn.Conn)) {
+	tests := []struct {
+		desc string
+		test func(*testing.T, conn.Conn)
+	}{
+		{"Insert should correctly insert a new segment", testInsert},
+		{"Insert should correctly update an existing segment", testUpdateExisting},
+		{"Insert should correctly ignore an older segment", testOlderIgnored},
+		{"Delete should correctly remove a path segment", testDelete},
+		{"DeleteWithIntf should remove all affected path segments", testDeleteWithIntf},
+		{"DeleteExpired should only remove expired path segments", testDeleteExpired},
+		{"Get should return the correct path segments", testGetMixed},
+		{"Get should return all path segments", testGetAll},
+		{"Get should return all path segments starting or ending at", testGetStartsAtEndsAt},
+		{"Get should return all path segments with given ifIDs", testGetWithIntfs},
+		{"Get should return all path segments with given HpCfgIDs", testGetWithHpCfgIDs},
+		{"Get should only return path segments valid until MinExpiry", testGetMinExpiry},
+		{"Get should only return path segments whose hop fields are all valid until MinExpiry",
+			testGetMinExpiryHops},
+		{"Get should only return path segments updated since MinLastUpdate",
+			testGetMinLastUpdate},
+		{"Get should return at most Limit path segments, newest first", testGetLimit},
+	}
+	for _, tc := range tests {
+		Convey(tc.desc, t, func() {
+			b := setup()
+			defer cleanup(b)
+			tc.test(t, b)
+		})
+	}
+}
+
+func testInsert(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	TS := uint32(10)
+	pseg, segID := AllocPathSegment(ifs1, TS)
+	// Call
+	inserted := insertSeg(t, ctx, b, pseg, types, hpCfgIDs)
+	// Check return value.
+	SoMsg("Inserted", inserted, ShouldEqual, 1)
+	// Check Insert.
+	res := get(t, ctx, b, &query.Params{SegID: segID})
+	SoMsg("Result count", len(res), ShouldEqual, 1)
+	checkResult(res[0], segID, TS, hpCfgIDs)
+	for _, segType := range types {
+		res = get(t, ctx, b, &query.Params{SegTypes: []proto.PathSegType{segType}})
+		SoMsg(fmt.Sprintf("Has type %v", segType), len(res), ShouldEqual, 1)
+	}
+}
+
+func testUpdateExisting(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	oldTS := uint32(10)
+	oldSeg, _ := AllocPathSegment(ifs1, oldTS)
+	newTS := uint32(20)
+	newSeg, newSegID := AllocPathSegment(ifs1, newTS)
+	insertSeg(t, ctx, b, oldSeg, types[:1], hpCfgIDs[:1])
+	// Call
+	inserted := insertSeg(t, ctx, b, newSeg, types, hpCfgIDs)
+	// Check return value.
+	SoMsg("Inserted", inserted, ShouldEqual, 1)
+	// Check that the segment got replaced and the types and ids merged.
+	res := get(t, ctx, b, nil)
+	SoMsg("Result count", len(res), ShouldEqual, 1)
+	checkResult(res[0], newSegID, newTS, hpCfgIDs)
+	res = get(t, ctx, b, &query.Params{SegTypes: types[1:]})
+	SoMsg("Has new type", len(res), ShouldEqual, 1)
+}
+
+func testOlderIgnored(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	newTS := uint32(20)
+	newSeg, newSegID := AllocPathSegment(ifs1, newTS)
+	oldTS := uint32(10)
+	oldSeg, _ := AllocPathSegment(ifs1, oldTS)
+	insertSeg(t, ctx, b, newSeg, types[:1], hpCfgIDs[:1])
+	// Call
+	inserted := insertSeg(t, ctx, b, oldSeg, types, hpCfgIDs)
+	// Check return value.
+	SoMsg("Inserted", inserted, ShouldEqual, 0)
+	// Check that nothing changed.
+	res := get(t, ctx, b, nil)
+	SoMsg("Result count", len(res), ShouldEqual, 1)
+	checkResult(res[0], newSegID, newTS, hpCfgIDs[:1])
+	res = get(t, ctx, b, &query.Params{SegTypes: types[1:]})
+	SoMsg("Type not added", len(res), ShouldEqual, 0)
+}
+
+func testDelete(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	TS := uint32(10)
+	pseg, segID := AllocPathSegment(ifs1, TS)
+	insertSeg(t, ctx, b, pseg, types, hpCfgIDs)
+	// Call
+	deleted, err := b.Delete(ctx, segID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Check return value.
+	SoMsg("Deleted", deleted, ShouldEqual, 1)
+	SoMsg("Empty", len(get(t, ctx, b, nil)), ShouldEqual, 0)
+	// Deleting again should not find anything.
+	deleted, err = b.Delete(ctx, segID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SoMsg("Deleted again", deleted, ShouldEqual, 0)
+}
+
+func testDeleteWithIntf(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	TS := uint32(10)
+	pseg1, _ := AllocPathSegment(ifs1, TS)
+	pseg2, segID2 := AllocPathSegment(ifs2, TS)
+	insertSeg(t, ctx, b, pseg1, types, hpCfgIDs)
+	insertSeg(t, ctx, b, pseg2, types, hpCfgIDs)
+	// Call
+	deleted, err := b.DeleteWithIntf(ctx, query.IntfSpec{IA: ia331, IfID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Check return value
+	SoMsg("Deleted", deleted, ShouldEqual, 2)
+	// Only pseg1 contains 1-ff00:0:331#6.
+	insertSeg(t, ctx, b, pseg1, types, hpCfgIDs)
+	insertSeg(t, ctx, b, pseg2, types, hpCfgIDs)
+	deleted, err = b.DeleteWithIntf(ctx, query.IntfSpec{IA: ia331, IfID: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SoMsg("Deleted only matching", deleted, ShouldEqual, 1)
+	res := get(t, ctx, b, nil)
+	SoMsg("Result count", len(res), ShouldEqual, 1)
+	checkResult(res[0], segID2, TS, hpCfgIDs)
+}
+
+func testDeleteExpired(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	oldTS := uint32(10)
+	newTS := uint32(time.Now().Unix())
+	pseg1, _ := AllocPathSegment(ifs1, oldTS)
+	pseg2, segID2 := AllocPathSegment(ifs2, newTS)
+	insertSeg(t, ctx, b, pseg1, types, hpCfgIDs)
+	insertSeg(t, ctx, b, pseg2, types, hpCfgIDs)
+	// Call
+	deleted, err := b.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Check return value.
+	SoMsg("Deleted", len(deleted), ShouldEqual, 1)
+	deletedID, _ := deleted[0].ID()
+	segID1, _ := pseg1.ID()
+	SoMsg("Deleted segment", deletedID, ShouldResemble, segID1)
+	res := get(t, ctx, b, nil)
+	SoMsg("Result count", len(res), ShouldEqual, 1)
+	checkResult(res[0], segID2, newTS, hpCfgIDs)
+}
+
+func testGetMixed(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	TS := uint32(10)
+	pseg1, segID1 := AllocPathSegment(ifs1, TS)
+	pseg2, _ := AllocPathSegment(ifs2, TS)
+	insertSeg(t, ctx, b, pseg1, types, hpCfgIDs)
+	insertSeg(t, ctx, b, pseg2, types[:1], hpCfgIDs[:1])
+	params := &query.Params{
+		SegID:    segID1,
+		SegTypes: []proto.PathSegType{proto.PathSegType_up},
+	}
+	// Call
+	res := get(t, ctx, b, params)
+	SoMsg("Result count", len(res), ShouldEqual, 1)
+	checkResult(res[0], segID1, TS, hpCfgIDs)
+	// Constraints are combined with AND.
+	params.SegTypes = []proto.PathSegType{proto.PathSegType_core}
+	SoMsg("No result", len(get(t, ctx, b, params)), ShouldEqual, 0)
+}
+
+func testGetAll(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	TS := uint32(10)
+	pseg1, segID1 := AllocPathSegment(ifs1, TS)
+	pseg2, segID2 := AllocPathSegment(ifs2, TS)
+	insertSeg(t, ctx, b, pseg1, types, hpCfgIDs)
+	insertSeg(t, ctx, b, pseg2, types[:1], hpCfgIDs[:1])
+	// Call
+	res := get(t, ctx, b, nil)
+	SoMsg("Result count", len(res), ShouldEqual, 2)
+	for _, r := range res {
+		resSegID, _ := r.Seg.ID()
+		if bytes.Equal(resSegID, segID1) {
+			SoMsg("HpCfgIDs match", r.HpCfgIDs, ShouldResemble, hpCfgIDs)
+		} else if bytes.Equal(resSegID, segID2) {
+			SoMsg("HpCfgIDs match", r.HpCfgIDs, ShouldResemble, hpCfgIDs[:1])
+		} else {
+			t.Fatal("Unexpected result", "seg", r.Seg)
+		}
+	}
+}
+
+func testGetStartsAtEndsAt(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	TS := uint32(10)
+	pseg1, _ := AllocPathSegment(ifs1, TS)
+	pseg2, _ := AllocPathSegment(ifs2, TS)
+	insertSeg(t, ctx, b, pseg1, types, hpCfgIDs)
+	insertSeg(t, ctx, b, pseg2, types[:1], hpCfgIDs[:1])
+	// Call
+	res := get(t, ctx, b, &query.Params{StartsAt: []addr.IA{ia330, ia332}})
+	SoMsg("Result count StartsAt", len(res), ShouldEqual, 2)
+	res = get(t, ctx, b, &query.Params{EndsAt: []addr.IA{ia330, ia332}})
+	SoMsg("Result count EndsAt", len(res), ShouldEqual, 2)
+	res = get(t, ctx, b, &query.Params{StartsAt: []addr.IA{ia332}})
+	SoMsg("Result count StartsAt other", len(res), ShouldEqual, 0)
+	res = get(t, ctx, b, &query.Params{EndsAt: []addr.IA{ia331}})
+	SoMsg("Result count EndsAt other", len(res), ShouldEqual, 0)
+}
+
+func testGetWithIntfs(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	TS := uint32(10)
+	pseg1, _ := AllocPathSegment(ifs1, TS)
+	pseg2, segID2 := AllocPathSegment(ifs2, TS)
+	insertSeg(t, ctx, b, pseg1, types, hpCfgIDs)
+	insertSeg(t, ctx, b, pseg2, types[:1], hpCfgIDs[:1])
+	params := &query.Params{
+		Intfs: []*query.IntfSpec{
+			{IA: ia330, IfID: 5},
+			{IA: ia332, IfID: 2},
+		},
+	}
+	// Call
+	res := get(t, ctx, b, params)
+	SoMsg("Result count", len(res), ShouldEqual, 2)
+	params = &query.Params{
+		Intfs: []*query.IntfSpec{
+			{IA: ia330, IfID: 4},
+		},
+	}
+	res = get(t, ctx, b, params)
+	SoMsg("Result count single", len(res), ShouldEqual, 1)
+	checkResult(res[0], segID2, TS, hpCfgIDs[:1])
+}
+
+func testGetWithHpCfgIDs(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	TS := uint32(10)
+	pseg1, segID1 := AllocPathSegment(ifs1, TS)
+	pseg2, _ := AllocPathSegment(ifs2, TS)
+	insertSeg(t, ctx, b, pseg1, types, hpCfgIDs)
+	insertSeg(t, ctx, b, pseg2, types[:1], hpCfgIDs[:1])
+	params := &query.Params{
+		HpCfgIDs: hpCfgIDs[1:],
+	}
+	// Call
+	res := get(t, ctx, b, params)
+	SoMsg("Result count", len(res), ShouldEqual, 1)
+	// Only the matching HpCfgIDs are returned.
+	checkResult(res[0], segID1, TS, hpCfgIDs[1:])
+}
+
+func testGetMinExpiry(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	now := time.Now()
+	oldTS := uint32(now.Add(-5 * time.Hour).Unix())
+	newTS := uint32(now.Unix())
+	pseg1, _ := AllocPathSegment(ifs1, oldTS)
+	pseg2, segID2 := AllocPathSegment(ifs2, newTS)
+	insertSeg(t, ctx, b, pseg1, types, hpCfgIDs)
+	insertSeg(t, ctx, b, pseg2, types, hpCfgIDs)
+	// Call
+	res := get(t, ctx, b, &query.Params{MinExpiry: now})
+	SoMsg("Result count now", len(res), ShouldEqual, 2)
+	res = get(t, ctx, b, &query.Params{MinExpiry: now.Add(2 * time.Hour)})
+	SoMsg("Result count in 2h", len(res), ShouldEqual, 1)
+	checkResult(res[0], segID2, newTS, hpCfgIDs)
+	res = get(t, ctx, b, &query.Params{MinExpiry: now.Add(spath.MaxTTL * time.Second)})
+	SoMsg("Result count after MaxTTL", len(res), ShouldEqual, 0)
+}
+
+func testGetMinExpiryHops(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	now := time.Now()
+	TS := uint32(now.Add(-2 * time.Hour).Unix())
+	// The first hop field of pseg1 expired after about one hour, the others
+	// are still valid.
+	pseg1, _ := allocPathSegment(ifs1, TS, []uint8{10, spath.DefaultHopFExpiry,
+		spath.DefaultHopFExpiry, spath.DefaultHopFExpiry})
+	pseg2, segID2 := AllocPathSegment(ifs2, TS)
+	insertSeg(t, ctx, b, pseg1, types, hpCfgIDs)
+	insertSeg(t, ctx, b, pseg2, types, hpCfgIDs)
+	// Call
+	res := get(t, ctx, b, &query.Params{MinExpiry: now})
+	SoMsg("Result count", len(res), ShouldEqual, 1)
+	checkResult(res[0], segID2, TS, hpCfgIDs)
+	res = get(t, ctx, b, &query.Params{MinExpiry: now.Add(-2 * time.Hour)})
+	SoMsg("Result count in the past", len(res), ShouldEqual, 2)
+}
+
+func testGetMinLastUpdate(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	TS := uint32(10)
+	pseg1, _ := AllocPathSegment(ifs1, TS)
+	pseg2, _ := AllocPathSegment(ifs2, TS)
+	insertSeg(t, ctx, b, pseg1, types, hpCfgIDs)
+	insertSeg(t, ctx, b, pseg2, types, hpCfgIDs)
+	// Call
+	res := get(t, ctx, b, &query.Params{MinLastUpdate: time.Now().Add(-time.Hour)})
+	SoMsg("Result count past", len(res), ShouldEqual, 2)
+	res = get(t, ctx, b, &query.Params{MinLastUpdate: time.Now().Add(time.Hour)})
+	SoMsg("Result count future", len(res), ShouldEqual, 0)
+}
+
+func testGetLimit(t *testing.T, b conn.Conn) {
+	ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+	defer cancelF()
+	TS := uint32(10)
+	pseg1, _ := AllocPathSegment(ifs1, TS)
+	pseg2, segID2 := AllocPathSegment(ifs2, TS)
+	pseg3, segID3 := AllocPathSegment(ifs3, TS)
+	insertSeg(t, ctx, b, pseg1, types, hpCfgIDs)
+	insertSeg(t, ctx, b, pseg2, types, hpCfgIDs)
+	insertSeg(t, ctx, b, pseg3, types[:1], hpCfgIDs[:1])
+	// Call
+	res := get(t, ctx, b, &query.Params{Limit: 2})
+	SoMsg("Result count", len(res), ShouldEqual, 2)
+	checkResult(res[0], segID3, TS, hpCfgIDs[:1])
+	checkResult(res[1], segID2, TS, hpCfgIDs)
+	// The limit is applied after all other constraints.
+	res = get(t, ctx, b, &query.Params{SegTypes: types[1:], Limit: 1})
+	SoMsg("Result count filtered", len(res), ShouldEqual, 1)
+	checkResult(res[0], segID2, TS, hpCfgIDs)
+	res = get(t, ctx, b, &query.Params{Limit: 5})
+	SoMsg("Result count large limit", len(res), ShouldEqual, 3)
+}
+
+func insertSeg(t *testing.T, ctx context.Context, b conn.Conn,
+	pseg *seg.PathSegment, types []proto.PathSegType, hpCfgIDs []*query.HPCfgID) int {
+
+	inserted, err := b.InsertWithHPCfgIDs(ctx, pseg, types, hpCfgIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inserted
+}
+
+func get(t *testing.T, ctx context.Context, b conn.Conn, params *query.Params) []*query.Result {
+	res, err := b.Get(ctx, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func checkResult(r *query.Result, segID common.RawBytes, ts uint32, hpCfgIDs []*query.HPCfgID) {
+	resSegID, _ := r.Seg.ID()
+	SoMsg("SegIDs match", resSegID, ShouldResemble, segID)
+	info, _ := r.Seg.InfoF()
+	SoMsg("Timestamps match", info.TsInt, ShouldEqual, ts)
+	SoMsg("HpCfgIDs match", r.HpCfgIDs, ShouldResemble, hpCfgIDs)
+}
+
+// AllocPathSegment creates a path segment 1-ff00:0:330 -> 1-ff00:0:331 ->
+// 1-ff00:0:332 with the given timestamp. ifs contains the ingress and egress
+// interface of each of the four hop fields, in this order.
+func AllocPathSegment(ifs []uint64, ts uint32) (*seg.PathSegment, common.RawBytes) {
+	return allocPathSegment(ifs, ts, nil)
+}
+
+// allocPathSegment is like AllocPathSegment, but if expTimes is not nil, it
+// contains the expiration time of each of the four hop fields.
+func allocPathSegment(ifs []uint64, ts uint32,
+	expTimes []uint8) (*seg.PathSegment, common.RawBytes) {
+
+	rawHops := make([][]byte, len(ifs)/2)
+	for i := 0; i < len(ifs)/2; i++ {
+		rawHops[i] = make([]byte, 8)
+		hf := spath.NewHopField(rawHops[i], common.IFIDType(ifs[2*i]),
+			common.IFIDType(ifs[2*i+1]))
+		if expTimes != nil {
+			hf.ExpTime = expTimes[i]
+			hf.Write()
+		}
+	}
+	ases := []*seg.ASEntry{
+		{
+			RawIA: ia330.IAInt(),
+			HopEntries: []*seg.HopEntry{
+				allocHopEntry(addr.IA{}, ia331, rawHops[0]),
+			},
+		},
+		{
+			RawIA: ia331.IAInt(),
+			HopEntries: []*seg.HopEntry{
+				allocHopEntry(ia330, ia332, rawHops[1]),
+				allocHopEntry(ia311, ia332, rawHops[2]),
+			},
+		},
+		{
+			RawIA: ia332.IAInt(),
+			HopEntries: []*seg.HopEntry{
+				allocHopEntry(ia331, addr.IA{}, rawHops[3]),
+			},
+		},
+	}
+	info := &spath.InfoField{
+		TsInt: ts,
+		ISD:   1,
+		Hops:  3,
+	}
+	pseg, _ := seg.NewSeg(info)
+	for _, ase := range ases {
+		if err := pseg.AddASEntry(ase, proto.SignType_none, nil); err != nil {
+			fmt.Printf("Error adding ASEntry: %v", err)
+		}
+	}
+	segID, _ := pseg.ID()
+	return pseg, segID
+}
+
+func allocHopEntry(inIA, outIA addr.IA, hopF common.RawBytes) *seg.HopEntry {
+	return &seg.HopEntry{
+		RawInIA:     inIA.IAInt(),
+		RawOutIA:    outIA.IAInt(),
+		RawHopField: hopF,
+	}
+}
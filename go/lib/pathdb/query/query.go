@@ -15,6 +15,8 @@
 package query
 
 import (
+	"time"
+
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/ctrl/seg"
@@ -45,6 +47,16 @@ type Params struct {
 	Intfs    []*IntfSpec
 	StartsAt []addr.IA
 	EndsAt   []addr.IA
+	// MinExpiry, if non-zero, restricts the result to segments that are
+	// valid at least until MinExpiry.
+	MinExpiry time.Time
+	// MinLastUpdate, if non-zero, restricts the result to segments that have
+	// been inserted or updated at or after MinLastUpdate.
+	MinLastUpdate time.Time
+	// Limit, if non-zero, restricts the result to the Limit most recently
+	// inserted or updated segments. The result is then ordered by freshness,
+	// newest first.
+	Limit int
 }
 
 type Result struct {
@@ -0,0 +1,98 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file contains the upgrade steps for older versions of the PathDB schema.
+
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/seg"
+	"github.com/scionproto/scion/go/lib/sqlite"
+)
+
+// Migrations contains the upgrade steps from older schema versions to
+// SchemaVersion.
+var Migrations = []sqlite.Migration{
+	{From: 1, Up: migrateV1},
+	{From: 2, Up: migrateV2},
+}
+
+// migrateV1 adds the Expiry column to the Segments table and computes it for
+// all stored segments.
+func migrateV1(tx *sql.Tx) error {
+	return addExpiryColumn(tx, "Expiry", (*seg.PathSegment).MaxExpiry)
+}
+
+// migrateV2 adds the MinExpiry column to the Segments table and computes it
+// for all stored segments.
+func migrateV2(tx *sql.Tx) error {
+	return addExpiryColumn(tx, "MinExpiry", (*seg.PathSegment).MinExpiry)
+}
+
+// addExpiryColumn adds column to the Segments table, and sets it to the
+// result of expiry for all stored segments.
+func addExpiryColumn(tx *sql.Tx, column string,
+	expiry func(*seg.PathSegment) (time.Time, error)) error {
+
+	_, err := tx.Exec(fmt.Sprintf(
+		"ALTER TABLE Segments ADD COLUMN %s INTEGER NOT NULL DEFAULT 0", column))
+	if err != nil {
+		return common.NewBasicError("Failed to add expiry column", err, "column", column)
+	}
+	rows, err := tx.Query("SELECT RowID, Segment FROM Segments")
+	if err != nil {
+		return common.NewBasicError("Failed to read segments", err)
+	}
+	expiries := make(map[int64]int64)
+	for rows.Next() {
+		var rowID int64
+		var rawSeg sql.RawBytes
+		if err := rows.Scan(&rowID, &rawSeg); err != nil {
+			rows.Close()
+			return common.NewBasicError("Failed to read segment", err)
+		}
+		pseg, err := seg.NewSegFromRaw(common.RawBytes(rawSeg))
+		if err != nil {
+			rows.Close()
+			return common.NewBasicError("Failed to parse segment", err, "rowID", rowID)
+		}
+		exp, err := expiry(pseg)
+		if err != nil {
+			rows.Close()
+			return common.NewBasicError("Failed to compute segment expiry", err,
+				"rowID", rowID)
+		}
+		expiries[rowID] = exp.Unix()
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return common.NewBasicError("Failed to read segments", err)
+	}
+	rows.Close()
+	// The updates are done after reading all rows, since the transaction
+	// cannot be used while the result set is open.
+	stmt := fmt.Sprintf("UPDATE Segments SET %s=? WHERE RowID=?", column)
+	for rowID, exp := range expiries {
+		if _, err := tx.Exec(stmt, exp, rowID); err != nil {
+			return common.NewBasicError("Failed to update segment expiry", err,
+				"rowID", rowID, "column", column)
+		}
+	}
+	return nil
+}
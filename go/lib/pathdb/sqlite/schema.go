@@ -20,13 +20,15 @@ const (
 	// SchemaVersion is the version of the SQLite schema understood by this backend.
 	// Whenever changes to the schema are made, this version number should be increased
 	// to prevent data corruption between incompatible database schemas.
-	SchemaVersion = 1
+	SchemaVersion = 3
 	// Schema is the SQLite database layout.
 	Schema = `CREATE TABLE Segments(
 		RowID INTEGER PRIMARY KEY AUTOINCREMENT,
 		SegID DATA UNIQUE NOT NULL,
 		LastUpdated INTEGER NOT NULL,
-		Segment DATA NOT NULL
+		Segment DATA NOT NULL,
+		Expiry INTEGER NOT NULL,
+		MinExpiry INTEGER NOT NULL
 	);
 	CREATE TABLE IntfToSeg(
 		IsdID INTEGER NOT NULL,
@@ -41,6 +41,8 @@ type segMeta struct {
 	SegID       common.RawBytes
 	LastUpdated time.Time
 	Seg         *seg.PathSegment
+	Expiry      time.Time
+	MinExpiry   time.Time
 }
 
 var _ conn.Conn = (*Backend)(nil)
@@ -53,9 +55,10 @@ type Backend struct {
 
 // New returns a new SQLite backend opening a database at the given path. If
 // no database exists a new database is be created. If the schema version of the
-// stored database is different from the one in schema.go, an error is returned.
+// stored database is older than the one in schema.go, it is upgraded using
+// Migrations. If it is newer, an error is returned.
 func New(path string) (*Backend, error) {
-	db, err := sqlite.New(path, Schema, SchemaVersion)
+	db, err := sqlite.New(path, Schema, SchemaVersion, Migrations...)
 	if err != nil {
 		return nil, err
 	}
@@ -117,6 +120,12 @@ func (b *Backend) InsertWithHPCfgIDs(ctx context.Context, pseg *seg.PathSegment,
 			// Update existing path segment.
 			meta.Seg = pseg
 			meta.LastUpdated = time.Now()
+			if meta.Expiry, err = pseg.MaxExpiry(); err != nil {
+				return 0, err
+			}
+			if meta.MinExpiry, err = pseg.MinExpiry(); err != nil {
+				return 0, err
+			}
 			if err := b.updateExisting(ctx, meta, segTypes, hpCfgIDs); err != nil {
 				return 0, err
 			}
@@ -132,20 +141,24 @@ func (b *Backend) InsertWithHPCfgIDs(ctx context.Context, pseg *seg.PathSegment,
 }
 
 func (b *Backend) get(ctx context.Context, segID common.RawBytes) (*segMeta, error) {
-	rows, err := b.db.QueryContext(ctx, "SELECT * FROM Segments WHERE SegID=?", segID)
+	rows, err := b.db.QueryContext(ctx,
+		"SELECT RowID, SegID, LastUpdated, Segment, Expiry, MinExpiry FROM Segments "+
+			"WHERE SegID=?", segID)
 	if err != nil {
 		return nil, common.NewBasicError("Failed to lookup segment", err)
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var meta segMeta
-		var lastUpdated int
+		var lastUpdated, expiry, minExpiry int64
 		var rawSeg sql.RawBytes
-		err = rows.Scan(&meta.RowID, &meta.SegID, &lastUpdated, &rawSeg)
+		err = rows.Scan(&meta.RowID, &meta.SegID, &lastUpdated, &rawSeg, &expiry, &minExpiry)
 		if err != nil {
 			return nil, common.NewBasicError("Failed to extract data", err)
 		}
-		meta.LastUpdated = time.Unix(int64(lastUpdated), 0)
+		meta.LastUpdated = time.Unix(lastUpdated, 0)
+		meta.Expiry = time.Unix(expiry, 0)
+		meta.MinExpiry = time.Unix(minExpiry, 0)
 		var err error
 		meta.Seg, err = seg.NewSegFromRaw(common.RawBytes(rawSeg))
 		if err != nil {
@@ -194,8 +207,10 @@ func (b *Backend) updateSeg(ctx context.Context, meta *segMeta) error {
 	if err != nil {
 		return err
 	}
-	stmtStr := `UPDATE Segments SET LastUpdated=?, Segment=? WHERE RowID=?`
-	_, err = b.tx.ExecContext(ctx, stmtStr, meta.LastUpdated.Unix(), packedSeg, meta.RowID)
+	stmtStr := `UPDATE Segments SET LastUpdated=?, Segment=?, Expiry=?, MinExpiry=?
+		WHERE RowID=?`
+	_, err = b.tx.ExecContext(ctx, stmtStr, meta.LastUpdated.Unix(), packedSeg,
+		meta.Expiry.Unix(), meta.MinExpiry.Unix(), meta.RowID)
 	if err != nil {
 		return common.NewBasicError("Failed to update segment", err)
 	}
@@ -240,9 +255,21 @@ func (b *Backend) insertFull(ctx context.Context, pseg *seg.PathSegment,
 	if err != nil {
 		return err
 	}
+	expiry, err := pseg.MaxExpiry()
+	if err != nil {
+		b.tx.Rollback()
+		return err
+	}
+	minExpiry, err := pseg.MinExpiry()
+	if err != nil {
+		b.tx.Rollback()
+		return err
+	}
 	// Insert path segment.
-	inst := `INSERT INTO Segments (SegID, LastUpdated, Segment) VALUES (?, ?, ?)`
-	res, err := b.tx.ExecContext(ctx, inst, segID, time.Now().Unix(), packedSeg)
+	inst := `INSERT INTO Segments (SegID, LastUpdated, Segment, Expiry, MinExpiry)
+		VALUES (?, ?, ?, ?, ?)`
+	res, err := b.tx.ExecContext(ctx, inst, segID, time.Now().Unix(), packedSeg,
+		expiry.Unix(), minExpiry.Unix())
 	if err != nil {
 		b.tx.Rollback()
 		return common.NewBasicError("Failed to insert path segment", err)
@@ -369,7 +396,8 @@ func (b *Backend) DeleteWithIntf(ctx context.Context, intf query.IntfSpec) (int,
 		return 0, err
 	}
 	delStmt := `DELETE FROM Segments WHERE EXISTS (
-		SELECT * FROM IntfToSeg WHERE IsdID=? AND AsID=? AND IntfID=?)`
+		SELECT * FROM IntfToSeg WHERE IsdID=? AND AsID=? AND IntfID=?
+		AND SegRowID=Segments.RowID)`
 	res, err := b.tx.ExecContext(ctx, delStmt, intf.IA.I, intf.IA.A, intf.IfID)
 	if err != nil {
 		b.tx.Rollback()
@@ -383,6 +411,64 @@ func (b *Backend) DeleteWithIntf(ctx context.Context, intf query.IntfSpec) (int,
 	return int(deleted), nil
 }
 
+func (b *Backend) DeleteExpired(ctx context.Context,
+	now time.Time) ([]*seg.PathSegment, error) {
+
+	b.Lock()
+	defer b.Unlock()
+	if b.db == nil {
+		return nil, common.NewBasicError("No database open", nil)
+	}
+	// Create new transaction
+	if err := b.begin(ctx); err != nil {
+		return nil, err
+	}
+	// Select the expired segments in the same transaction, so that exactly
+	// the returned segments are deleted.
+	segs, err := b.getExpired(ctx, now)
+	if err != nil {
+		b.tx.Rollback()
+		return nil, err
+	}
+	_, err = b.tx.ExecContext(ctx, "DELETE FROM Segments WHERE Expiry < ?", now.Unix())
+	if err != nil {
+		b.tx.Rollback()
+		return nil, common.NewBasicError("Failed to delete expired segments", err)
+	}
+	// Commit transaction
+	if err := b.commit(); err != nil {
+		return nil, err
+	}
+	return segs, nil
+}
+
+// getExpired returns the segments that expired before now. It must be called
+// within a transaction.
+func (b *Backend) getExpired(ctx context.Context, now time.Time) ([]*seg.PathSegment, error) {
+	rows, err := b.tx.QueryContext(ctx, "SELECT Segment FROM Segments WHERE Expiry < ?",
+		now.Unix())
+	if err != nil {
+		return nil, common.NewBasicError("Failed to lookup expired segments", err)
+	}
+	defer rows.Close()
+	var segs []*seg.PathSegment
+	for rows.Next() {
+		var rawSeg sql.RawBytes
+		if err := rows.Scan(&rawSeg); err != nil {
+			return nil, common.NewBasicError("Failed to extract data", err)
+		}
+		pseg, err := seg.NewSegFromRaw(common.RawBytes(rawSeg))
+		if err != nil {
+			return nil, common.NewBasicError("Failed to parse segment", err)
+		}
+		segs = append(segs, pseg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, common.NewBasicError("Failed to lookup expired segments", err)
+	}
+	return segs, nil
+}
+
 func (b *Backend) Get(ctx context.Context, params *query.Params) ([]*query.Result, error) {
 	b.RLock()
 	defer b.RUnlock()
@@ -436,6 +522,36 @@ func (b *Backend) buildQuery(params *query.Params) string {
 	if params == nil {
 		return strings.Join(query, "\n")
 	}
+	joins, where := buildFilters(params)
+	order := "ORDER BY s.LastUpdated DESC, s.RowID DESC"
+	if params.Limit > 0 {
+		// The limit applies to segments and not to the rows of the join, so
+		// the matching segments are selected in a subquery.
+		subQ := []string{
+			"SELECT s.RowID FROM Segments s",
+			"JOIN HpCfgIds h ON h.SegRowID=s.RowID",
+		}
+		subQ = append(subQ, joins...)
+		if len(where) > 0 {
+			subQ = append(subQ, fmt.Sprintf("WHERE %s", strings.Join(where, " AND\n")))
+		}
+		subQ = append(subQ, "GROUP BY s.RowID", order, fmt.Sprintf("LIMIT %d", params.Limit))
+		where = append(where, fmt.Sprintf("s.RowID IN (%s)", strings.Join(subQ, "\n")))
+	}
+	// Assemble the query.
+	query = append(query, joins...)
+	if len(where) > 0 {
+		query = append(query, fmt.Sprintf("WHERE %s", strings.Join(where, " AND\n")))
+	}
+	if params.Limit > 0 {
+		query = append(query, order)
+	}
+	return strings.Join(query, "\n")
+}
+
+// buildFilters returns the joins and where clauses that implement the
+// constraints in params.
+func buildFilters(params *query.Params) ([]string, []string) {
 	joins := []string{}
 	where := []string{}
 	if len(params.SegID) > 0 {
@@ -482,12 +598,11 @@ func (b *Backend) buildQuery(params *query.Params) string {
 		}
 		where = append(where, fmt.Sprintf("(%s)", strings.Join(subQ, " OR ")))
 	}
-	// Assemble the query.
-	if len(joins) > 0 {
-		query = append(query, strings.Join(joins, "\n"))
+	if !params.MinExpiry.IsZero() {
+		where = append(where, fmt.Sprintf("s.MinExpiry>=%d", params.MinExpiry.Unix()))
 	}
-	if len(where) > 0 {
-		query = append(query, fmt.Sprintf("WHERE %s", strings.Join(where, " AND\n")))
+	if !params.MinLastUpdate.IsZero() {
+		where = append(where, fmt.Sprintf("s.LastUpdated>=%d", params.MinLastUpdate.Unix()))
 	}
-	return strings.Join(query, "\n")
+	return joins, where
 }
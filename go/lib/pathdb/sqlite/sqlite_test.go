@@ -29,8 +29,11 @@ import (
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/ctrl/seg"
+	"github.com/scionproto/scion/go/lib/pathdb/conn"
+	"github.com/scionproto/scion/go/lib/pathdb/pathdbtest"
 	"github.com/scionproto/scion/go/lib/pathdb/query"
 	"github.com/scionproto/scion/go/lib/spath"
+	"github.com/scionproto/scion/go/lib/sqlite"
 	"github.com/scionproto/scion/go/proto"
 )
 
@@ -368,6 +371,33 @@ func Test_DeleteWithIntf(t *testing.T) {
 		// Check return value
 		SoMsg("Deleted", deleted, ShouldEqual, 2)
 	})
+	Convey("DeleteWithIntf should not remove segments without the interface", t, func() {
+		// Setup
+		b, tmpF := setupDB(t)
+		defer b.db.Close()
+		defer os.Remove(tmpF)
+		TS := uint32(10)
+		ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+		defer cancelF()
+		pseg1, _ := allocPathSegment(ifs1, TS)
+		pseg2, segID2 := allocPathSegment(ifs2, TS)
+		insertSeg(t, ctx, b, pseg1, types, hpCfgIDs)
+		insertSeg(t, ctx, b, pseg2, types, hpCfgIDs)
+		// Call, only pseg1 contains 1-ff00:0:331#6
+		deleted, err := b.DeleteWithIntf(ctx, query.IntfSpec{IA: ia331, IfID: 6})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Check return value
+		SoMsg("Deleted", deleted, ShouldEqual, 1)
+		// Check that pseg2 is still present
+		checkSegments(t, b, 2, segID2, TS)
+		res, err := b.Get(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		SoMsg("Result count", len(res), ShouldEqual, 1)
+	})
 }
 
 func Test_GetMixed(t *testing.T) {
@@ -553,3 +583,67 @@ func Test_OpenNewer(t *testing.T) {
 		SoMsg("Err returned", err, ShouldNotBeNil)
 	})
 }
+
+func Test_MigrateV1(t *testing.T) {
+	Convey("New should compute the expiry of segments stored with schema version 1", t, func() {
+		tmpF := tempFilename(t)
+		defer os.Remove(tmpF)
+		TS := uint32(10)
+		pseg, segID := allocPathSegment(ifs1, TS)
+		packedSeg, err := pseg.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		db, err := sqlite.New(tmpF, schemaV1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.Exec("INSERT INTO Segments (SegID, LastUpdated, Segment) VALUES (?, ?, ?)",
+			segID, time.Now().Unix(), packedSeg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+		// Call
+		b, err := New(tmpF)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer b.db.Close()
+		// Test
+		expectedExpiry, _ := pseg.MaxExpiry()
+		expectedMinExpiry, _ := pseg.MinExpiry()
+		meta, err := b.get(context.Background(), segID)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("Expiry", meta.Expiry.Unix(), ShouldEqual, expectedExpiry.Unix())
+		SoMsg("MinExpiry", meta.MinExpiry.Unix(), ShouldEqual, expectedMinExpiry.Unix())
+		var version int
+		if err := b.db.QueryRow("PRAGMA user_version;").Scan(&version); err != nil {
+			t.Fatal(err)
+		}
+		SoMsg("Version", version, ShouldEqual, SchemaVersion)
+	})
+}
+
+// schemaV1 is the layout of the Segments table in schema version 1.
+const schemaV1 = `CREATE TABLE Segments(
+		RowID INTEGER PRIMARY KEY AUTOINCREMENT,
+		SegID DATA UNIQUE NOT NULL,
+		LastUpdated INTEGER NOT NULL,
+		Segment DATA NOT NULL
+	);`
+
+func TestPathDBSuite(t *testing.T) {
+	tmpFiles := make(map[conn.Conn]string)
+	setup := func() conn.Conn {
+		b, tmpF := setupDB(t)
+		tmpFiles[b] = tmpF
+		return b
+	}
+	cleanup := func(c conn.Conn) {
+		c.(*Backend).db.Close()
+		os.Remove(tmpFiles[c])
+		delete(tmpFiles, c)
+	}
+	pathdbtest.TestPathDB(t, setup, cleanup)
+}
@@ -17,6 +17,7 @@
 package pathmgr
 
 import (
+	"bytes"
 	"sync"
 	"time"
 
@@ -35,6 +36,11 @@ type cacheEntry struct {
 	fs filterSet
 	// Time when paths were last changed
 	timestamp time.Time
+	// Number of consecutive lookups that returned no paths
+	failures int
+	// If the last lookup returned no paths, queries return no paths without
+	// contacting SCIOND until this time
+	negExpiry time.Time
 }
 
 func (ce *cacheEntry) isWatched() bool {
@@ -50,18 +56,32 @@ type cache struct {
 	maxAge time.Duration
 	// Revocation table mapping uifid to paths that contain the uifid
 	revTable *revTable
+	// Wait times after lookups that returned no paths
+	backoff *backoff
+	// Ranker used to order watched paths
+	ranker Ranker
+	// Health of the watched paths, as reported by a Prober
+	health map[spathmeta.PathKey]PathHealth
+	// Named policies last passed to setFilters
+	policies map[string]*pktcls.ActionFilterPaths
 }
 
-func newCache(maxAge time.Duration) *cache {
+func newCache(maxAge time.Duration, b *backoff) *cache {
 	return &cache{
 		m:        make(map[IAKey]*cacheEntry),
 		maxAge:   maxAge,
 		revTable: newRevTable(),
+		backoff:  b,
 	}
 }
 
-// update the set of paths between src and dst to aps.
-func (c *cache) update(src, dst addr.IA, aps spathmeta.AppPathSet) {
+// update the set of paths between src and dst to aps. If aps is empty, the
+// lookup failed, and the result is cached for an exponentially increasing
+// duration. update returns whether the lookup failed, and if so, the duration
+// for which the failure is cached.
+func (c *cache) update(src, dst addr.IA,
+	aps spathmeta.AppPathSet) (bool, time.Duration) {
+
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	entry, ok := c.getEntry(src, dst)
@@ -74,10 +94,21 @@ func (c *cache) update(src, dst addr.IA, aps spathmeta.AppPathSet) {
 	// Update revocation lists
 	c.revTable.updatePathSet(aps)
 	entry.timestamp = time.Now()
+	if len(aps) != 0 {
+		entry.failures = 0
+		entry.negExpiry = time.Time{}
+		return false, 0
+	}
+	entry.failures++
+	wait := c.backoff.duration(entry.failures)
+	entry.negExpiry = entry.timestamp.Add(wait)
+	return true, wait
 }
 
 // getAPS returns the paths between src and dst. If the paths are stale or
-// missing, the second return value is false.
+// missing, the second return value is false. If the last lookup found no
+// paths and its negative cache entry has not expired yet, an empty set is
+// returned and the second return value is true.
 func (c *cache) getAPS(src, dst addr.IA) (spathmeta.AppPathSet, bool) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -90,6 +121,9 @@ func (c *cache) getAPS(src, dst addr.IA) (spathmeta.AppPathSet, bool) {
 				aps.Add(path.Entry)
 			}
 		}
+		if len(entry.aps) == 0 && now.Before(entry.negExpiry) {
+			return aps, true
+		}
 		if now.Sub(entry.timestamp) > c.maxAge || len(aps) == 0 {
 			// Paths are missing or stale, caller should ask the resolver to do a blocking request
 			return nil, false
@@ -120,6 +154,8 @@ func (c *cache) watch(src, dst addr.IA, filter *pktcls.ActionFilterPaths) {
 			pp:       filter,
 			refCount: 1,
 		}
+		pf.sp.ranker = c.ranker
+		pf.sp.health = c.health
 		pf.update(entry.aps)
 		entry.fs[key] = pf
 	} else {
@@ -178,6 +214,7 @@ func (c *cache) removeWatch(src, dst addr.IA, filter *pktcls.ActionFilterPaths)
 		pf.refCount--
 		if pf.refCount == 0 {
 			delete(entry.fs, key)
+			pf.sp.close()
 		}
 		return nil
 	}
@@ -185,6 +222,81 @@ func (c *cache) removeWatch(src, dst addr.IA, filter *pktcls.ActionFilterPaths)
 		"src", src, "dst", dst)
 }
 
+// setFilters replaces the filters of watches with the filter of the same
+// name in filters (if one exists), and refilters the watched paths. Watches of
+// a policy that was passed to the previous call but is missing from filters
+// keep their old filter, and a warning is logged.
+func (c *cache) setFilters(filters map[string]*pktcls.ActionFilterPaths) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for iaKey, entry := range c.m {
+		for key, pf := range entry.fs {
+			if filter, ok := filters[key]; ok {
+				pf.pp = filter
+				pf.update(entry.aps)
+				continue
+			}
+			if _, ok := c.policies[key]; ok {
+				log.Warn("Watched policy was removed, keeping its last version",
+					"policy", key, "watch", iaKey)
+			}
+		}
+	}
+	c.policies = filters
+}
+
+// setRanker changes the ranker used to order watched paths to r, and
+// reorders the paths of all existing watches.
+func (c *cache) setRanker(r Ranker) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.ranker = r
+	for _, entry := range c.m {
+		for _, pf := range entry.fs {
+			pf.sp.setRanker(r)
+		}
+	}
+}
+
+// getRanker returns the ranker used to order paths.
+func (c *cache) getRanker() Ranker {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.ranker
+}
+
+// watchedPaths returns the paths of all watched src-dst pairs. Paths without
+// interfaces are not included.
+func (c *cache) watchedPaths() spathmeta.AppPathSet {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	aps := make(spathmeta.AppPathSet)
+	for _, entry := range c.m {
+		if !entry.isWatched() {
+			continue
+		}
+		for key, ap := range entry.aps {
+			if len(ap.Entry.Path.Interfaces) > 0 {
+				aps[key] = ap
+			}
+		}
+	}
+	return aps
+}
+
+// setHealth replaces the health of the watched paths with health, and reorders
+// the paths of all watches. Callers must not change health afterwards.
+func (c *cache) setHealth(health map[spathmeta.PathKey]PathHealth) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.health = health
+	for _, entry := range c.m {
+		for _, pf := range entry.fs {
+			pf.sp.setHealth(health)
+		}
+	}
+}
+
 // revoke all paths containing uifid from the cache.
 func (c *cache) revoke(u uifid) {
 	c.mutex.Lock()
@@ -201,6 +313,27 @@ func (c *cache) revoke(u uifid) {
 	}
 }
 
+// invalidate removes the path between src and dst with forwarding path
+// fwdPath from the cache. It returns true if such a path was found.
+func (c *cache) invalidate(src, dst addr.IA, fwdPath common.RawBytes) bool {
+	if len(fwdPath) == 0 {
+		return false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok := c.getEntry(src, dst)
+	if !ok {
+		return false
+	}
+	for _, ap := range entry.aps {
+		if bytes.Equal(ap.Entry.Path.FwdPath, fwdPath) {
+			c.remove(src, dst, ap)
+			return true
+		}
+	}
+	return false
+}
+
 // remove (internal) one path from the set of paths between src and dst.
 func (c *cache) remove(src, dst addr.IA, ap *spathmeta.AppPath) {
 	entry, ok := c.getEntry(src, dst)
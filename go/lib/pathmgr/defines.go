@@ -16,6 +16,7 @@ package pathmgr
 
 import (
 	"fmt"
+	"math/rand"
 	"time"
 
 	"github.com/scionproto/scion/go/lib/addr"
@@ -69,9 +70,49 @@ func (pf *pathFilter) update(aps spathmeta.AppPathSet) {
 	// Filter the paths according to the current predicate
 	newAPS := make(spathmeta.AppPathSet)
 	if pf.pp == nil {
-		newAPS = aps
+		// Copy the set, because the cache removes revoked paths from it in
+		// place, and the SyncPaths object needs the old set to compute the
+		// change.
+		for k, v := range aps {
+			newAPS[k] = v
+		}
 	} else {
 		newAPS = pf.pp.Act(aps).(spathmeta.AppPathSet)
 	}
 	pf.sp.update(newAPS)
 }
+
+// backoff computes wait times after consecutive failed path lookups.
+type backoff struct {
+	// Wait time after the first failure
+	min time.Duration
+	// Maximum wait time
+	max time.Duration
+	// Fraction of the wait time that is randomly added or subtracted
+	jitter float64
+}
+
+// duration returns the wait time after the n-th consecutive failure. The wait
+// time doubles with each failure, starting from min, up to max. Jitter never
+// reduces the wait time below half of its undisturbed value, so the wait time
+// is always positive.
+func (b *backoff) duration(n int) time.Duration {
+	d := b.min
+	for i := 1; i < n && d < b.max; i++ {
+		d *= 2
+	}
+	if d > b.max {
+		d = b.max
+	}
+	if b.jitter > 0 {
+		floor := d / 2
+		if floor <= 0 {
+			floor = time.Nanosecond
+		}
+		d += time.Duration((2*rand.Float64() - 1) * b.jitter * float64(d))
+		if d < floor {
+			d = floor
+		}
+	}
+	return d
+}
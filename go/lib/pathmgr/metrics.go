@@ -0,0 +1,84 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pathmgr
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/scionproto/scion/go/lib/prom"
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+)
+
+var ProbesSent *prometheus.CounterVec
+var ProbesLost *prometheus.CounterVec
+var PathRTT *prometheus.GaugeVec
+var PathDown *prometheus.GaugeVec
+
+// InitMetrics registers the path prober metrics. If it is not called before a
+// Prober is started, no metrics are exported.
+func InitMetrics(namespace string, constLabels prometheus.Labels) {
+	lNames := []string{"path"}
+	newCVec := func(name, help string) *prometheus.CounterVec {
+		v := prom.NewCounterVec(namespace, "pathmgr", name, help, constLabels, lNames)
+		prometheus.MustRegister(v)
+		return v
+	}
+	newGVec := func(name, help string) *prometheus.GaugeVec {
+		v := prom.NewGaugeVec(namespace, "pathmgr", name, help, constLabels, lNames)
+		prometheus.MustRegister(v)
+		return v
+	}
+	ProbesSent = newCVec("probes_sent_total", "Number of sent path probes.")
+	ProbesLost = newCVec("probes_lost_total", "Number of path probes without reply.")
+	PathRTT = newGVec("path_rtt_seconds", "Round-trip time of the last successful probe.")
+	PathDown = newGVec("path_down", "Whether the path is down (1) or up (0).")
+}
+
+// pathLabel returns the value of the path label for ap.
+func pathLabel(ap *spathmeta.AppPath) string {
+	return fmt.Sprintf("%v", ap.Entry.Path.Interfaces)
+}
+
+// observeProbe updates the metrics of the path with the given label after a
+// probe.
+func observeProbe(label string, h PathHealth, success bool) {
+	if ProbesSent == nil {
+		return
+	}
+	ProbesSent.WithLabelValues(label).Inc()
+	if success {
+		PathRTT.WithLabelValues(label).Set(h.RTT.Seconds())
+	} else {
+		ProbesLost.WithLabelValues(label).Inc()
+	}
+	down := 0.0
+	if h.Down {
+		down = 1
+	}
+	PathDown.WithLabelValues(label).Set(down)
+}
+
+// forgetProbes removes the metrics of the path with the given label.
+func forgetProbes(label string) {
+	if ProbesSent == nil {
+		return
+	}
+	ProbesSent.DeleteLabelValues(label)
+	ProbesLost.DeleteLabelValues(label)
+	PathRTT.DeleteLabelValues(label)
+	PathDown.DeleteLabelValues(label)
+}
@@ -24,11 +24,33 @@
 // thread-safe SyncPaths object; calling Load on the object returns the data
 // associated with the watch, which includes the set of paths. When updating
 // paths, the resolver will atomically change the value within the SyncPaths
-// object. The data can be accessed by calling Load again.
+// object. The data can be accessed by calling Load again. The paths are also
+// available in order of preference, as defined by the resolver's Ranker (see
+// SetRanker); by default, shorter paths are preferred.
+//
+// Paths of watched destinations can be actively probed by running a Prober.
+// Probing results (RTT, loss and whether a path is down) are exposed in the
+// Health field of SyncPathsData, and as Prometheus metrics (see InitMetrics).
+//
+// Named path policies can be loaded from a policy file using LoadPolicies.
+// After attaching them to a resolver via SetPolicies, paths can be queried and
+// watched by policy name using QueryPolicy and WatchPolicy.
+//
+// If a lookup finds no paths to a destination, the empty result is cached, and
+// queries for the destination return no paths without contacting SCIOND until
+// the cache entry expires. The lifetime of the entry starts at ErrorRefire and
+// doubles after each consecutive failed lookup, up to MaxErrorRefire (see
+// Timers). Watched destinations are looked up again whenever the entry
+// expires.
 //
 // An example of how this package can be used can be found in the associated
 // infra test file.
 //
+// Paths are requested from the sciond.Service passed to New. To run without
+// SCIOND, an in-process service that fetches path segments directly from the
+// path servers of the local AS can be used instead (see
+// segfetcher.NewService).
+//
 // If the connection to SCIOND fails, the resolver automatically attempts to
 // reestablish the connection. During this period, paths are not expired. Paths
 // will be transparently refreshed after reconnecting to SCIOND.
@@ -55,8 +77,18 @@ import (
 type Timers struct {
 	// Wait time after a successful path lookup (for periodic lookups)
 	NormalRefire time.Duration
-	// Wait time after a failed (error or empty) path lookup (for periodic lookups)
+	// Wait time after a failed (error or empty) path lookup. After each
+	// further consecutive failure, the wait time doubles up to MaxErrorRefire.
+	// Until the wait time passes, queries for the destination return no paths
+	// without contacting SCIOND.
 	ErrorRefire time.Duration
+	// Maximum wait time after consecutive failed path lookups
+	MaxErrorRefire time.Duration
+	// Fraction of the wait time after failed path lookups that is randomly
+	// added or subtracted, to avoid synchronized retries. Must be at most 1;
+	// negative values disable jitter. The jittered wait time is at least half
+	// of the wait time without jitter.
+	Jitter float64
 	// Duration after which a path is considered stale
 	MaxAge time.Duration
 }
@@ -64,8 +96,12 @@ type Timers struct {
 const (
 	// Default wait time after a successful path lookup (for periodic lookups)
 	DefaultNormalRefire = time.Minute
-	// Default wait time after a failed path lookup (for periodic lookups)
+	// Default wait time after a failed path lookup
 	DefaultErrorRefire = time.Second
+	// Default maximum wait time after consecutive failed path lookups
+	DefaultMaxErrorRefire = time.Minute
+	// Default fraction of random jitter for wait times after failed lookups
+	DefaultJitter = 0.1
 	// Default time after which a path is considered stale
 	DefaultMaxAge = 6 * time.Hour
 )
@@ -77,6 +113,18 @@ func setDefaultTimers(timers *Timers) {
 	if timers.ErrorRefire == 0 {
 		timers.ErrorRefire = DefaultErrorRefire
 	}
+	if timers.MaxErrorRefire == 0 {
+		timers.MaxErrorRefire = DefaultMaxErrorRefire
+	}
+	if timers.MaxErrorRefire < timers.ErrorRefire {
+		timers.MaxErrorRefire = timers.ErrorRefire
+	}
+	if timers.Jitter == 0 {
+		timers.Jitter = DefaultJitter
+	}
+	if timers.Jitter > 1 {
+		timers.Jitter = 1
+	}
 	if timers.MaxAge == 0 {
 		timers.MaxAge = DefaultMaxAge
 	}
@@ -91,6 +139,8 @@ type PR struct {
 	// Used for keeping track of which queries need to be sent
 	requestQueue chan *resolverRequest
 	cache        *cache
+	// Named path policies, set via SetPolicies
+	policies *Policies
 	log.Logger
 }
 
@@ -99,7 +149,8 @@ type PR struct {
 // uninitialized, it is assigned the corresponding default value (see package
 // constants). When a query for a path older than maxAge reaches the resolver,
 // SCIOND is used to refresh the path. New returns with an error if a
-// connection to SCIOND could not be established.
+// connection to SCIOND could not be established. Parameter srvc can also be
+// an in-process replacement for SCIOND, e.g., a segfetcher.Service.
 func New(srvc sciond.Service, timers *Timers, logger log.Logger) (*PR, error) {
 	sciondConn, err := srvc.Connect()
 	if err != nil {
@@ -114,7 +165,11 @@ func New(srvc sciond.Service, timers *Timers, logger log.Logger) (*PR, error) {
 		sciondService: srvc,
 		requestQueue:  make(chan *resolverRequest, queryChanCap),
 		Logger:        logger.New("lib", "PathResolver"),
-		cache:         newCache(timers.MaxAge),
+		cache: newCache(timers.MaxAge, &backoff{
+			min:    timers.ErrorRefire,
+			max:    timers.MaxErrorRefire,
+			jitter: timers.Jitter,
+		}),
 	}
 	// Start resolver, which periodically refreshes paths for registered
 	// destinations
@@ -124,7 +179,6 @@ func New(srvc sciond.Service, timers *Timers, logger log.Logger) (*PR, error) {
 		cache:         pr.cache,
 		requestQueue:  pr.requestQueue,
 		normalRefire:  timers.NormalRefire,
-		errorRefire:   timers.ErrorRefire,
 	}
 	go r.run()
 	return pr, nil
@@ -157,6 +211,27 @@ func (r *PR) Query(src, dst addr.IA) spathmeta.AppPathSet {
 	return spathmeta.AppPathSet{}
 }
 
+// QueryRanked is similar to Query, but returns the paths in order of
+// preference according to the resolver's ranker (see SetRanker).
+func (r *PR) QueryRanked(src, dst addr.IA) []*spathmeta.AppPath {
+	return Rank(r.Query(src, dst), r.cache.getRanker())
+}
+
+// QueryPreferred returns the path between src and dst with key pref if it is
+// available. Otherwise, the most preferred path according to the resolver's
+// ranker (see SetRanker) is returned. If there are no paths, nil is returned.
+func (r *PR) QueryPreferred(src, dst addr.IA, pref spathmeta.PathKey) *spathmeta.AppPath {
+	aps := r.Query(src, dst)
+	if ap, ok := aps[pref]; ok {
+		return ap
+	}
+	ranked := Rank(aps, r.cache.getRanker())
+	if len(ranked) == 0 {
+		return nil
+	}
+	return ranked[0]
+}
+
 func (r *PR) QueryFilter(src, dst addr.IA, filter *pktcls.ActionFilterPaths) spathmeta.AppPathSet {
 	aps := r.Query(src, dst)
 	// Delete paths that do not match the predicate
@@ -216,6 +291,73 @@ func (r *PR) UnwatchFilter(src, dst addr.IA, filter *pktcls.ActionFilterPaths) e
 	return r.cache.removeWatch(src, dst, filter)
 }
 
+// SetRanker changes the order of preference between paths to the one defined
+// by ranker. The paths of existing and future watches, and the paths returned
+// by QueryRanked are ordered accordingly. If ranker is nil, DefaultRanker is
+// used.
+func (r *PR) SetRanker(ranker Ranker) {
+	r.cache.setRanker(ranker)
+}
+
+// SetPolicies makes the policies in p available to QueryPolicy, WatchPolicy
+// and UnwatchPolicy. When p is reloaded, paths watched via WatchPolicy are
+// refiltered according to the new policies. Because watches are identified by
+// filter name, filters passed to WatchFilter should not share names with
+// policies. SetPolicies must be called at most once.
+func (r *PR) SetPolicies(p *Policies) {
+	r.Lock()
+	defer r.Unlock()
+	r.policies = p
+	p.subscribe(r.cache.setFilters)
+}
+
+// Policies returns the policies set via SetPolicies, or nil if none were set.
+func (r *PR) Policies() *Policies {
+	r.Lock()
+	defer r.Unlock()
+	return r.policies
+}
+
+// QueryPolicy returns the paths between src and dst that adhere to the policy
+// called name.
+func (r *PR) QueryPolicy(src, dst addr.IA, name string) (spathmeta.AppPathSet, error) {
+	filter, err := r.getPolicy(name)
+	if err != nil {
+		return nil, err
+	}
+	return r.QueryFilter(src, dst, filter), nil
+}
+
+// WatchPolicy is similar to WatchFilter, but filters the paths using the
+// policy called name. The paths in the returned SyncPaths object always adhere
+// to the most recently loaded version of the policy.
+func (r *PR) WatchPolicy(src, dst addr.IA, name string) (*SyncPaths, error) {
+	filter, err := r.getPolicy(name)
+	if err != nil {
+		return nil, err
+	}
+	return r.WatchFilter(src, dst, filter)
+}
+
+// UnwatchPolicy deletes a watch previously registered via WatchPolicy.
+func (r *PR) UnwatchPolicy(src, dst addr.IA, name string) error {
+	// Watches are identified by name, so this also works if the policy was
+	// removed from the policy file in the meantime.
+	return r.UnwatchFilter(src, dst, &pktcls.ActionFilterPaths{Name: name})
+}
+
+func (r *PR) getPolicy(name string) (*pktcls.ActionFilterPaths, error) {
+	policies := r.Policies()
+	if policies == nil {
+		return nil, common.NewBasicError("No policies set", nil, "policy", name)
+	}
+	filter, ok := policies.Get(name)
+	if !ok {
+		return nil, common.NewBasicError("Policy not found", nil, "policy", name)
+	}
+	return filter, nil
+}
+
 // Revoke asynchronously informs SCIOND about a revocation and flushes any
 // paths containing the revoked IFID.
 func (r *PR) Revoke(revInfo common.RawBytes) {
@@ -229,6 +371,15 @@ func (r *PR) Revoke(revInfo common.RawBytes) {
 	}()
 }
 
+// InvalidatePath removes the path between src and dst with forwarding path
+// fwdPath from the cache, e.g., because an SCMP error reported that the path is
+// no longer usable. Watches are updated immediately, and fresh paths are
+// requested from SCIOND on the next query once no paths are left. The return
+// value is true if the path was found.
+func (r *PR) InvalidatePath(src, dst addr.IA, fwdPath common.RawBytes) bool {
+	return r.cache.invalidate(src, dst, fwdPath)
+}
+
 func (r *PR) revoke(b common.RawBytes) {
 	sRevInfo, err := path_mgmt.NewSignedRevInfoFromRaw(b)
 	if err != nil {
@@ -83,6 +83,63 @@ func TestQueryFilter(t *testing.T) {
 	})
 }
 
+func TestQueryNegativeCache(t *testing.T) {
+	Convey("Query with 0 paths, the empty result is cached until it expires", t, func() {
+		g := graph.NewDefaultGraph()
+		g.RemoveLink(1019)
+		pm := NewPR(t, g, 1000, 100, 1000)
+		srcIA := xtest.MustParseIA("1-ff00:0:133")
+		dstIA := xtest.MustParseIA("1-ff00:0:131")
+
+		aps := pm.Query(srcIA, dstIA)
+		SoMsg("aps len", len(aps), ShouldEqual, 0)
+		g.AddLink("1-ff00:0:133", 1019, "1-ff00:0:132", 1910, false)
+		aps = pm.Query(srcIA, dstIA)
+		SoMsg("aps len cached", len(aps), ShouldEqual, 0)
+		<-time.After(150 * time.Millisecond)
+		aps = pm.Query(srcIA, dstIA)
+		SoMsg("aps len expired", len(aps), ShouldEqual, 1)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	Convey("Backoff should double the wait time up to the maximum", t, func() {
+		b := &backoff{min: time.Second, max: 5 * time.Second}
+		SoMsg("1", b.duration(1), ShouldEqual, time.Second)
+		SoMsg("2", b.duration(2), ShouldEqual, 2*time.Second)
+		SoMsg("3", b.duration(3), ShouldEqual, 4*time.Second)
+		SoMsg("4", b.duration(4), ShouldEqual, 5*time.Second)
+		SoMsg("100", b.duration(100), ShouldEqual, 5*time.Second)
+	})
+	Convey("Backoff with jitter should stay within bounds", t, func() {
+		b := &backoff{min: time.Second, max: 5 * time.Second, jitter: 0.5}
+		for i := 0; i < 100; i++ {
+			d := b.duration(2)
+			SoMsg("lower", d, ShouldBeGreaterThanOrEqualTo, time.Second)
+			SoMsg("upper", d, ShouldBeLessThanOrEqualTo, 3*time.Second)
+		}
+	})
+	Convey("Backoff with maximum jitter should stay positive", t, func() {
+		b := &backoff{min: time.Second, max: 5 * time.Second, jitter: 1}
+		for i := 0; i < 100; i++ {
+			d := b.duration(2)
+			SoMsg("lower", d, ShouldBeGreaterThanOrEqualTo, time.Second)
+			SoMsg("upper", d, ShouldBeLessThanOrEqualTo, 4*time.Second)
+		}
+		c := newCache(time.Minute, b)
+		src := xtest.MustParseIA("1-ff00:0:133")
+		dst := xtest.MustParseIA("1-ff00:0:131")
+		for i := 0; i < 100; i++ {
+			failed, wait := c.update(src, dst, make(spathmeta.AppPathSet))
+			SoMsg("failed", failed, ShouldBeTrue)
+			SoMsg("wait", wait, ShouldBeGreaterThan, 0)
+			aps, ok := c.getAPS(src, dst)
+			SoMsg("negatively cached", ok, ShouldBeTrue)
+			SoMsg("aps", len(aps), ShouldEqual, 0)
+		}
+	})
+}
+
 func TestRegister(t *testing.T) {
 	Convey("Register for path, receive 0 responses", t, func() {
 		g := graph.NewDefaultGraph()
@@ -212,6 +269,34 @@ func TestRevoke(t *testing.T) {
 	})
 }
 
+func TestInvalidatePath(t *testing.T) {
+	Convey("Invalidated paths are removed from watches", t, func() {
+		g := graph.NewDefaultGraph()
+		g.AddLink("1-ff00:0:133", 101902, "1-ff00:0:132", 191002, false)
+		pm := NewPR(t, g, 10000, 10000, 10000)
+		srcIA := xtest.MustParseIA("1-ff00:0:133")
+		dstIA := xtest.MustParseIA("1-ff00:0:131")
+		sp, err := pm.Watch(srcIA, dstIA)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("aps", len(sp.Load().APS), ShouldEqual, 2)
+		// Paths of the mock service have no forwarding path, so add distinct ones
+		var i byte
+		for _, ap := range sp.Load().APS {
+			i++
+			ap.Entry.Path.FwdPath = []byte{i}
+		}
+		ap := sp.Load().APS.GetAppPath("")
+		SoMsg("invalidate", pm.InvalidatePath(srcIA, dstIA, ap.Entry.Path.FwdPath), ShouldBeTrue)
+		aps := sp.Load().APS
+		SoMsg("watch", len(aps), ShouldEqual, 1)
+		SoMsg("removed", aps[ap.Key()], ShouldBeNil)
+		SoMsg("unknown path",
+			pm.InvalidatePath(srcIA, dstIA, ap.Entry.Path.FwdPath), ShouldBeFalse)
+		SoMsg("unknown dst", pm.InvalidatePath(srcIA, xtest.MustParseIA("1-ff00:0:132"),
+			ap.Entry.Path.FwdPath), ShouldBeFalse)
+	})
+}
+
 func NewPR(t *testing.T, g *graph.Graph, normalRefire, errorRefire, maxAge int) *PR {
 	t.Helper()
 
@@ -0,0 +1,170 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pathmgr
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/hpkt"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/scmp"
+	"github.com/scionproto/scion/go/lib/sock/reliable"
+	"github.com/scionproto/scion/go/lib/spath"
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+	"github.com/scionproto/scion/go/lib/spkt"
+)
+
+var _ Pinger = (*SCMPPinger)(nil)
+
+// SCMPPinger is a Pinger that sends SCMP echo requests via the dispatcher.
+// Replies are matched to requests by their sequence number, so a single
+// SCMPPinger can be used concurrently.
+type SCMPPinger struct {
+	conn      *reliable.Conn
+	ia        addr.IA
+	host      addr.HostAddr
+	remote    func(addr.IA) addr.HostAddr
+	id        uint64
+	mutex     sync.Mutex
+	seq       uint16
+	pending   map[uint16]chan time.Duration
+	closeChan chan struct{}
+}
+
+// NewSCMPPinger registers ia and host with the dispatcher at dispatcherPath,
+// and returns a Pinger that sends echo requests from that address. Function
+// remote returns the host in the destination AS that the requests are sent
+// to; if remote is nil, requests are sent to the path service anycast
+// address.
+func NewSCMPPinger(dispatcherPath string, ia addr.IA, host addr.HostAddr,
+	remote func(addr.IA) addr.HostAddr) (*SCMPPinger, error) {
+	if dispatcherPath == "" {
+		dispatcherPath = reliable.DefaultDispPath
+	}
+	conn, _, err := reliable.Register(dispatcherPath, ia, &reliable.AppAddr{Addr: host},
+		nil, addr.SvcNone)
+	if err != nil {
+		return nil, common.NewBasicError("Unable to register with dispatcher", err)
+	}
+	if remote == nil {
+		remote = func(addr.IA) addr.HostAddr { return addr.SvcPS }
+	}
+	p := &SCMPPinger{
+		conn:      conn,
+		ia:        ia,
+		host:      host,
+		remote:    remote,
+		id:        rand.Uint64(),
+		pending:   make(map[uint16]chan time.Duration),
+		closeChan: make(chan struct{}),
+	}
+	go p.recv()
+	return p, nil
+}
+
+func (p *SCMPPinger) Ping(path *spathmeta.AppPath, timeout time.Duration) (time.Duration, error) {
+	dst := path.Entry.Path.DstIA()
+	fwdPath := spath.New(path.Entry.Path.FwdPath)
+	if err := fwdPath.InitOffsets(); err != nil {
+		return 0, common.NewBasicError("Unable to initialize path", err)
+	}
+	reply := make(chan time.Duration, 1)
+	p.mutex.Lock()
+	seq := p.seq
+	p.seq++
+	p.pending[seq] = reply
+	p.mutex.Unlock()
+	defer func() {
+		p.mutex.Lock()
+		delete(p.pending, seq)
+		p.mutex.Unlock()
+	}()
+
+	hdr, pld := scmp.NewEchoRequest(&scmp.InfoEcho{Id: p.id, Seq: seq})
+	pkt := &spkt.ScnPkt{
+		DstIA:   dst,
+		SrcIA:   p.ia,
+		DstHost: p.remote(dst),
+		SrcHost: p.host,
+		Path:    fwdPath,
+		L4:      hdr,
+		Pld:     pld,
+	}
+	b := make(common.RawBytes, common.MaxMTU)
+	n, err := hpkt.WriteScnPkt(pkt, b)
+	if err != nil {
+		return 0, common.NewBasicError("Unable to serialize SCION packet", err)
+	}
+	nextHop := &reliable.AppAddr{
+		Addr: path.Entry.HostInfo.Host(),
+		Port: path.Entry.HostInfo.Port,
+	}
+	if _, err := p.conn.WriteTo(b[:n], nextHop); err != nil {
+		return 0, common.NewBasicError("Dispatcher write error", err)
+	}
+	select {
+	case rtt := <-reply:
+		return rtt, nil
+	case <-time.After(timeout):
+		return 0, common.NewBasicError("Echo request timed out", nil,
+			"dst", dst, "seq", seq, "timeout", timeout)
+	}
+}
+
+// recv reads echo replies and passes their round-trip times to the waiting
+// Ping calls. recv returns when reading from the dispatcher fails, e.g.,
+// because the pinger was closed.
+func (p *SCMPPinger) recv() {
+	defer log.LogPanicAndExit()
+	b := make(common.RawBytes, common.MaxMTU)
+	pkt := &spkt.ScnPkt{}
+	for {
+		n, err := p.conn.Read(b)
+		if err != nil {
+			select {
+			case <-p.closeChan:
+			default:
+				log.Error("Unable to read echo replies", "err", err)
+			}
+			return
+		}
+		now := time.Now()
+		if err := hpkt.ParseScnPkt(pkt, b[:n]); err != nil {
+			log.Warn("Unable to parse echo reply", "err", err)
+			continue
+		}
+		hdr, info, err := scmp.ParseEcho(pkt.L4, pkt.Pld)
+		if err != nil || info.Id != p.id ||
+			hdr.Class != scmp.C_General || hdr.Type != scmp.T_G_EchoReply {
+			continue
+		}
+		p.mutex.Lock()
+		if reply, ok := p.pending[info.Seq]; ok {
+			reply <- now.Sub(hdr.Time())
+			delete(p.pending, info.Seq)
+		}
+		p.mutex.Unlock()
+	}
+}
+
+// Close unregisters the pinger from the dispatcher.
+func (p *SCMPPinger) Close() error {
+	close(p.closeChan)
+	return p.conn.Close()
+}
@@ -0,0 +1,172 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pathmgr
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/pktcls"
+)
+
+// Policies is a thread-safe collection of named path policies, loaded from a
+// policy file. A policy file contains a JSON encoded pktcls.ActionMap in which
+// every action is an ActionFilterPaths, e.g.:
+//   {
+//       "no-isd-3": {
+//           "ActionFilterPaths": {
+//               "CondPathDenyList": {
+//                   "DL": "3-0#0"
+//               }
+//           }
+//       }
+//   }
+//
+// The file can be reloaded while the policies are in use. If a reload fails,
+// the previously loaded policies remain in effect. Path resolvers that use the
+// policies (see PR.SetPolicies) refilter their watched paths after each
+// successful reload. Watches of a policy that is removed from the file keep
+// using its last loaded version until they are removed.
+type Policies struct {
+	mutex    sync.RWMutex
+	fileName string
+	// Modification time of the file when it was last loaded
+	modTime time.Time
+	m       map[string]*pktcls.ActionFilterPaths
+	// Functions called with the new policies after each successful reload
+	subscribers []func(map[string]*pktcls.ActionFilterPaths)
+}
+
+// LoadPolicies loads the policies in fileName.
+func LoadPolicies(fileName string) (*Policies, error) {
+	p := &Policies{fileName: fileName}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Reload reads the policy file again and replaces the current policies with
+// its contents.
+func (p *Policies) Reload() error {
+	info, err := os.Stat(p.fileName)
+	if err != nil {
+		return common.NewBasicError("Unable to stat policy file", err, "file", p.fileName)
+	}
+	b, err := ioutil.ReadFile(p.fileName)
+	if err != nil {
+		return common.NewBasicError("Unable to read policy file", err, "file", p.fileName)
+	}
+	m, err := parsePolicies(b)
+	if err != nil {
+		return common.NewBasicError("Unable to parse policy file", err, "file", p.fileName)
+	}
+	p.mutex.Lock()
+	p.m = m
+	p.modTime = info.ModTime()
+	subscribers := p.subscribers
+	p.mutex.Unlock()
+	for _, f := range subscribers {
+		f(m)
+	}
+	return nil
+}
+
+func parsePolicies(b []byte) (map[string]*pktcls.ActionFilterPaths, error) {
+	var am pktcls.ActionMap
+	if err := json.Unmarshal(b, &am); err != nil {
+		return nil, err
+	}
+	m := make(map[string]*pktcls.ActionFilterPaths)
+	for name, action := range am {
+		filter, ok := action.(*pktcls.ActionFilterPaths)
+		if !ok {
+			return nil, common.NewBasicError("Unsupported policy action", nil,
+				"policy", name, "type", action.Type())
+		}
+		if name == matchAll {
+			return nil, common.NewBasicError("Reserved policy name", nil, "policy", name)
+		}
+		m[name] = filter
+	}
+	return m, nil
+}
+
+// Run checks the policy file for modifications every interval, and reloads
+// it when its modification time changes. Reload errors are logged. Run
+// returns after stop is closed.
+func (p *Policies) Run(interval time.Duration, stop <-chan struct{}) {
+	defer log.LogPanicAndExit()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(p.fileName)
+			if err != nil {
+				log.Error("Unable to stat policy file", "file", p.fileName, "err", err)
+				continue
+			}
+			p.mutex.RLock()
+			modified := !info.ModTime().Equal(p.modTime)
+			p.mutex.RUnlock()
+			if !modified {
+				continue
+			}
+			if err := p.Reload(); err != nil {
+				log.Error("Unable to reload policies", "err", err)
+				continue
+			}
+			log.Info("Reloaded policies", "file", p.fileName)
+		}
+	}
+}
+
+// Get returns the policy called name.
+func (p *Policies) Get(name string) (*pktcls.ActionFilterPaths, bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	filter, ok := p.m[name]
+	return filter, ok
+}
+
+// Names returns the sorted names of all policies.
+func (p *Policies) Names() []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	var names []string
+	for name := range p.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// subscribe calls f with the current policies, and registers f to be called
+// with the new policies after each successful reload.
+func (p *Policies) subscribe(f func(map[string]*pktcls.ActionFilterPaths)) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	f(p.m)
+	p.subscribers = append(p.subscribers, f)
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pathmgr
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/lib/xtest/graph"
+)
+
+const policyTemplate = `{
+    "%s": {
+        "ActionFilterPaths": {
+            "CondPathDenyList": {
+                "DL": "%s"
+            }
+        }
+    }
+}`
+
+func writePolicyFile(t *testing.T, fileName, name, denyList string) {
+	t.Helper()
+
+	err := ioutil.WriteFile(fileName, []byte(fmt.Sprintf(policyTemplate, name, denyList)),
+		0644)
+	xtest.FailOnErr(t, err)
+}
+
+func TestLoadPolicies(t *testing.T) {
+	Convey("Load policies from file", t, func() {
+		fileName := xtest.MustTempFileName("", "pathmgr-policy")
+		defer os.Remove(fileName)
+		writePolicyFile(t, fileName, "no-isd-2", "2-0#0")
+		p, err := LoadPolicies(fileName)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("names", p.Names(), ShouldResemble, []string{"no-isd-2"})
+		filter, ok := p.Get("no-isd-2")
+		SoMsg("ok", ok, ShouldBeTrue)
+		SoMsg("name", filter.GetName(), ShouldEqual, "no-isd-2")
+		_, ok = p.Get("foo")
+		SoMsg("missing", ok, ShouldBeFalse)
+		Convey("A failed reload keeps the old policies", func() {
+			err := ioutil.WriteFile(fileName, []byte("{"), 0644)
+			xtest.FailOnErr(t, err)
+			SoMsg("err", p.Reload(), ShouldNotBeNil)
+			SoMsg("names", p.Names(), ShouldResemble, []string{"no-isd-2"})
+		})
+		Convey("Reserved names are rejected", func() {
+			writePolicyFile(t, fileName, matchAll, "2-0#0")
+			SoMsg("err", p.Reload(), ShouldNotBeNil)
+		})
+	})
+	Convey("Loading a missing file fails", t, func() {
+		_, err := LoadPolicies("/nonexistent/policies.json")
+		SoMsg("err", err, ShouldNotBeNil)
+	})
+}
+
+func TestWatchPolicy(t *testing.T) {
+	Convey("Watch paths using a policy", t, func() {
+		fileName := xtest.MustTempFileName("", "pathmgr-policy")
+		defer os.Remove(fileName)
+		writePolicyFile(t, fileName, "policy", "2-0#0")
+		p, err := LoadPolicies(fileName)
+		xtest.FailOnErr(t, err)
+
+		g := graph.NewDefaultGraph()
+		pm := NewPR(t, g, 500, 500, 1000)
+		srcIA := xtest.MustParseIA("1-ff00:0:133")
+		dstIA := xtest.MustParseIA("1-ff00:0:131")
+
+		_, err = pm.WatchPolicy(srcIA, dstIA, "policy")
+		SoMsg("no policies err", err, ShouldNotBeNil)
+
+		pm.SetPolicies(p)
+		_, err = pm.WatchPolicy(srcIA, dstIA, "foo")
+		SoMsg("unknown policy err", err, ShouldNotBeNil)
+
+		sp, err := pm.WatchPolicy(srcIA, dstIA, "policy")
+		SoMsg("err", err, ShouldBeNil)
+		apsCheckPaths("watch", sp.Load().APS,
+			"[1-ff00:0:133#1019 1-ff00:0:132#1910 "+
+				"1-ff00:0:132#1916 1-ff00:0:131#1619]")
+		aps, err := pm.QueryPolicy(srcIA, dstIA, "policy")
+		SoMsg("query err", err, ShouldBeNil)
+		apsCheckPaths("query", aps,
+			"[1-ff00:0:133#1019 1-ff00:0:132#1910 "+
+				"1-ff00:0:132#1916 1-ff00:0:131#1619]")
+
+		Convey("Reloading the policy refilters the watched paths", func() {
+			writePolicyFile(t, fileName, "policy", "1-ff00:0:132#0")
+			SoMsg("err", p.Reload(), ShouldBeNil)
+			apsCheckPaths("watch", sp.Load().APS)
+			aps, err := pm.QueryPolicy(srcIA, dstIA, "policy")
+			SoMsg("query err", err, ShouldBeNil)
+			apsCheckPaths("query", aps)
+		})
+		Convey("Removing the policy keeps its last version", func() {
+			writePolicyFile(t, fileName, "other", "1-ff00:0:132#0")
+			SoMsg("err", p.Reload(), ShouldBeNil)
+			apsCheckPaths("watch", sp.Load().APS,
+				"[1-ff00:0:133#1019 1-ff00:0:132#1910 "+
+					"1-ff00:0:132#1916 1-ff00:0:131#1619]")
+			SoMsg("unwatch err", pm.UnwatchPolicy(srcIA, dstIA, "policy"), ShouldBeNil)
+		})
+		Convey("Unwatch the policy", func() {
+			SoMsg("err", pm.UnwatchPolicy(srcIA, dstIA, "policy"), ShouldBeNil)
+			SoMsg("err again", pm.UnwatchPolicy(srcIA, dstIA, "policy"), ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,192 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pathmgr
+
+import (
+	"sync"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+)
+
+const (
+	// Default time between two probes of the same path
+	DefaultProbeInterval = 5 * time.Second
+	// Default time to wait for a probe reply
+	DefaultProbeTimeout = time.Second
+	// Default number of consecutive failed probes after which a path is down
+	DefaultProbeDownAfter = 3
+)
+
+// A Pinger sends probes along paths.
+type Pinger interface {
+	// Ping sends a probe to the destination AS of path, along path, and
+	// waits for the reply. It returns the round-trip time, or an error if no
+	// reply was received within timeout.
+	Ping(path *spathmeta.AppPath, timeout time.Duration) (time.Duration, error)
+}
+
+// ProbeConfig is used to customize the behavior of a Prober.
+type ProbeConfig struct {
+	// Time between two probes of the same path
+	Interval time.Duration
+	// Time to wait for a probe reply
+	Timeout time.Duration
+	// Number of consecutive failed probes after which a path is down
+	DownAfter int
+}
+
+func setDefaultProbeConfig(cfg *ProbeConfig) {
+	if cfg.Interval == 0 {
+		cfg.Interval = DefaultProbeInterval
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = DefaultProbeTimeout
+	}
+	if cfg.DownAfter == 0 {
+		cfg.DownAfter = DefaultProbeDownAfter
+	}
+}
+
+// PathHealth contains the probing results of a path.
+type PathHealth struct {
+	// Round-trip time of the last successful probe
+	RTT time.Duration
+	// Number of sent probes
+	Sent uint64
+	// Number of probes that did not receive a reply
+	Lost uint64
+	// Number of failed probes since the last successful one
+	ConsecutiveFailures int
+	// True if the path is considered down
+	Down bool
+	// Time of the last probe
+	LastProbe time.Time
+}
+
+// Loss returns the fraction of probes that did not receive a reply.
+func (h PathHealth) Loss() float64 {
+	if h.Sent == 0 {
+		return 0
+	}
+	return float64(h.Lost) / float64(h.Sent)
+}
+
+// Prober periodically probes all paths of the watched destinations of a path
+// resolver. The results are exposed via the Health field of SyncPathsData.
+// Paths that are down are ranked after all other paths.
+type Prober struct {
+	pr     *PR
+	pinger Pinger
+	cfg    ProbeConfig
+	// Health of the probed paths, only accessed by the probing goroutine
+	health map[spathmeta.PathKey]PathHealth
+	// Metric labels of the probed paths
+	labels map[spathmeta.PathKey]string
+}
+
+// NewProber creates a new prober for the watched paths of pr. Parameter cfg
+// can be used to customize the prober; if any field is left uninitialized,
+// it is assigned the corresponding default value.
+func NewProber(pr *PR, pinger Pinger, cfg *ProbeConfig) *Prober {
+	if cfg == nil {
+		cfg = &ProbeConfig{}
+	}
+	setDefaultProbeConfig(cfg)
+	return &Prober{
+		pr:     pr,
+		pinger: pinger,
+		cfg:    *cfg,
+		health: make(map[spathmeta.PathKey]PathHealth),
+		labels: make(map[spathmeta.PathKey]string),
+	}
+}
+
+// Run probes the watched paths every probe interval. Run returns after stop
+// is closed.
+func (p *Prober) Run(stop <-chan struct{}) {
+	defer log.LogPanicAndExit()
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			p.probe()
+		}
+	}
+}
+
+// probe sends one probe along each watched path, and publishes the results.
+func (p *Prober) probe() {
+	paths := p.pr.cache.watchedPaths()
+	type result struct {
+		rtt time.Duration
+		err error
+	}
+	var mutex sync.Mutex
+	results := make(map[spathmeta.PathKey]result)
+	var wg sync.WaitGroup
+	for key, path := range paths {
+		wg.Add(1)
+		go func(key spathmeta.PathKey, path *spathmeta.AppPath) {
+			defer log.LogPanicAndExit()
+			defer wg.Done()
+			rtt, err := p.pinger.Ping(path, p.cfg.Timeout)
+			mutex.Lock()
+			results[key] = result{rtt: rtt, err: err}
+			mutex.Unlock()
+		}(key, path)
+	}
+	wg.Wait()
+	now := time.Now()
+	health := make(map[spathmeta.PathKey]PathHealth)
+	for key, res := range results {
+		h := p.health[key]
+		h.Sent++
+		h.LastProbe = now
+		if res.err != nil {
+			h.Lost++
+			h.ConsecutiveFailures++
+			if h.ConsecutiveFailures >= p.cfg.DownAfter && !h.Down {
+				h.Down = true
+				log.Info("Path is down", "path", paths[key].Entry.Path, "err", res.err)
+			}
+		} else {
+			h.RTT = res.rtt
+			h.ConsecutiveFailures = 0
+			if h.Down {
+				h.Down = false
+				log.Info("Path is up again", "path", paths[key].Entry.Path)
+			}
+		}
+		health[key] = h
+		if _, ok := p.labels[key]; !ok {
+			p.labels[key] = pathLabel(paths[key])
+		}
+		observeProbe(p.labels[key], h, res.err == nil)
+	}
+	// Forget paths that are no longer watched
+	for key, label := range p.labels {
+		if _, ok := health[key]; !ok {
+			forgetProbes(label)
+			delete(p.labels, key)
+		}
+	}
+	p.health = health
+	p.pr.cache.setHealth(health)
+}
@@ -0,0 +1,109 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pathmgr
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/lib/xtest/graph"
+)
+
+var _ Pinger = (*testPinger)(nil)
+
+// testPinger fails all pings along paths that traverse a broken interface.
+type testPinger struct {
+	mutex  sync.Mutex
+	broken common.IFIDType
+}
+
+func (p *testPinger) Ping(path *spathmeta.AppPath, _ time.Duration) (time.Duration, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, iface := range path.Entry.Path.Interfaces {
+		if iface.IfID == p.broken {
+			return 0, common.NewBasicError("Timeout", nil)
+		}
+	}
+	return 10 * time.Millisecond, nil
+}
+
+func (p *testPinger) setBroken(ifid common.IFIDType) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.broken = ifid
+}
+
+func TestProber(t *testing.T) {
+	Convey("Probe the watched paths", t, func() {
+		g := graph.NewDefaultGraph()
+		g.AddLink("1-ff00:0:133", 101902, "1-ff00:0:132", 191002, false)
+		pm := NewPR(t, g, 500, 500, 1000)
+		srcIA := xtest.MustParseIA("1-ff00:0:133")
+		dstIA := xtest.MustParseIA("1-ff00:0:131")
+		sp, err := pm.Watch(srcIA, dstIA)
+		xtest.FailOnErr(t, err)
+		var broken, working *spathmeta.AppPath
+		for _, ap := range sp.Load().APS {
+			if ap.Entry.Path.Interfaces[0].IfID == 1019 {
+				broken = ap
+			} else {
+				working = ap
+			}
+		}
+		SoMsg("paths", broken, ShouldNotBeNil)
+		SoMsg("paths", working, ShouldNotBeNil)
+		// Make the broken path the preferred one
+		pm.SetRanker(RankerFunc(func(a, b *spathmeta.AppPath) bool {
+			return a == broken && b != broken
+		}))
+
+		pinger := &testPinger{}
+		prober := NewProber(pm, pinger, &ProbeConfig{DownAfter: 2})
+		prober.probe()
+		data := sp.Load()
+		SoMsg("rtt", data.Health[broken.Key()].RTT, ShouldEqual, 10*time.Millisecond)
+		SoMsg("sent", data.Health[broken.Key()].Sent, ShouldEqual, 1)
+		SoMsg("preferred", data.GetAppPath(""), ShouldEqual, broken)
+
+		pinger.setBroken(1019)
+		prober.probe()
+		data = sp.Load()
+		h := data.Health[broken.Key()]
+		SoMsg("failures", h.ConsecutiveFailures, ShouldEqual, 1)
+		SoMsg("loss", h.Loss(), ShouldEqual, 0.5)
+		SoMsg("not yet down", h.Down, ShouldBeFalse)
+		SoMsg("still preferred", data.GetAppPath(broken.Key()), ShouldEqual, broken)
+
+		prober.probe()
+		data = sp.Load()
+		SoMsg("down", data.Health[broken.Key()].Down, ShouldBeTrue)
+		SoMsg("working up", data.Health[working.Key()].Down, ShouldBeFalse)
+		SoMsg("ranked", data.Ranked, ShouldResemble, []*spathmeta.AppPath{working, broken})
+		SoMsg("fallback", data.GetAppPath(broken.Key()), ShouldEqual, working)
+
+		pinger.setBroken(0)
+		prober.probe()
+		data = sp.Load()
+		SoMsg("up again", data.Health[broken.Key()].Down, ShouldBeFalse)
+		SoMsg("preferred again", data.GetAppPath(""), ShouldEqual, broken)
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pathmgr
+
+import (
+	"time"
+
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+)
+
+// A Ranker defines the order of preference between paths.
+type Ranker interface {
+	// Less returns true if path a is preferred over path b.
+	Less(a, b *spathmeta.AppPath) bool
+}
+
+// RankerFunc is an adapter that allows ordinary functions to be used as
+// Rankers.
+type RankerFunc func(a, b *spathmeta.AppPath) bool
+
+func (f RankerFunc) Less(a, b *spathmeta.AppPath) bool {
+	return f(a, b)
+}
+
+var (
+	// RankHops prefers paths with fewer hops.
+	RankHops Ranker = RankerFunc(func(a, b *spathmeta.AppPath) bool {
+		return len(a.Entry.Path.Interfaces) < len(b.Entry.Path.Interfaces)
+	})
+	// RankMTU prefers paths with larger MTUs.
+	RankMTU Ranker = RankerFunc(func(a, b *spathmeta.AppPath) bool {
+		return a.Entry.Path.Mtu > b.Entry.Path.Mtu
+	})
+	// RankExpiry prefers paths that expire later.
+	RankExpiry Ranker = RankerFunc(func(a, b *spathmeta.AppPath) bool {
+		return a.Entry.Path.ExpTime > b.Entry.Path.ExpTime
+	})
+	// DefaultRanker is used by path resolvers without a custom ranker. It
+	// prefers shorter paths, then paths that expire later, then paths with
+	// larger MTUs.
+	DefaultRanker = Chain(RankHops, RankExpiry, RankMTU)
+)
+
+// RankLatency returns a Ranker that prefers paths with lower latency, as
+// reported by function latency. Paths with unknown latency are ranked after
+// paths with known latency.
+func RankLatency(latency func(*spathmeta.AppPath) (time.Duration, bool)) Ranker {
+	return RankerFunc(func(a, b *spathmeta.AppPath) bool {
+		la, okA := latency(a)
+		lb, okB := latency(b)
+		if okA != okB {
+			return okA
+		}
+		return okA && la < lb
+	})
+}
+
+// RankScore returns a Ranker that prefers paths with higher scores, as
+// computed by function score.
+func RankScore(score func(*spathmeta.AppPath) float64) Ranker {
+	return RankerFunc(func(a, b *spathmeta.AppPath) bool {
+		return score(a) > score(b)
+	})
+}
+
+// Chain returns a Ranker that orders paths according to the first ranker in
+// rankers that has a preference between them.
+func Chain(rankers ...Ranker) Ranker {
+	return RankerFunc(func(a, b *spathmeta.AppPath) bool {
+		for _, r := range rankers {
+			if r.Less(a, b) {
+				return true
+			}
+			if r.Less(b, a) {
+				return false
+			}
+		}
+		return false
+	})
+}
+
+// Rank returns the paths in aps in order of preference according to r. Paths
+// without a preference between them are ordered by key. If r is nil,
+// DefaultRanker is used.
+func Rank(aps spathmeta.AppPathSet, r Ranker) []*spathmeta.AppPath {
+	if r == nil {
+		r = DefaultRanker
+	}
+	return aps.Sorted(r.Less)
+}
@@ -0,0 +1,138 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pathmgr
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/lib/xtest/graph"
+)
+
+// newTestAPS returns a set with three paths:
+//   "short": 2 interfaces, MTU 1280, expires in 1h
+//   "long":  4 interfaces, MTU 1500, expires in 2h
+//   "mid":   4 interfaces, MTU 1400, expires in 2h
+func newTestAPS() (spathmeta.AppPathSet, map[string]*spathmeta.AppPath) {
+	now := time.Now()
+	newEntry := func(ifids []common.IFIDType, mtu uint16, exp time.Duration) *sciond.PathReplyEntry {
+		var ifaces []sciond.PathInterface
+		for _, ifid := range ifids {
+			ifaces = append(ifaces, sciond.PathInterface{
+				RawIsdas: xtest.MustParseIA("1-ff00:0:110").IAInt(),
+				IfID:     ifid,
+			})
+		}
+		return &sciond.PathReplyEntry{
+			Path: &sciond.FwdPathMeta{
+				Interfaces: ifaces,
+				Mtu:        mtu,
+				ExpTime:    uint32(now.Add(exp).Unix()),
+			},
+		}
+	}
+	aps := make(spathmeta.AppPathSet)
+	paths := map[string]*spathmeta.AppPath{
+		"short": aps.Add(newEntry([]common.IFIDType{1, 2}, 1280, time.Hour)),
+		"long":  aps.Add(newEntry([]common.IFIDType{3, 4, 5, 6}, 1500, 2*time.Hour)),
+		"mid":   aps.Add(newEntry([]common.IFIDType{7, 8, 9, 10}, 1400, 2*time.Hour)),
+	}
+	return aps, paths
+}
+
+func TestRank(t *testing.T) {
+	aps, paths := newTestAPS()
+	latencies := map[*spathmeta.AppPath]time.Duration{
+		paths["long"]: 10 * time.Millisecond,
+		paths["mid"]:  20 * time.Millisecond,
+	}
+	latency := func(ap *spathmeta.AppPath) (time.Duration, bool) {
+		l, ok := latencies[ap]
+		return l, ok
+	}
+	testCases := []struct {
+		Name     string
+		Ranker   Ranker
+		Expected []string
+	}{
+		{"default", nil, []string{"short", "long", "mid"}},
+		{"mtu", RankMTU, []string{"long", "mid", "short"}},
+		{"expiry then hops", Chain(RankExpiry, RankHops, RankMTU),
+			[]string{"long", "mid", "short"}},
+		{"latency", RankLatency(latency), []string{"long", "mid", "short"}},
+		{"score", RankScore(func(ap *spathmeta.AppPath) float64 {
+			return -float64(ap.Entry.Path.Mtu)
+		}), []string{"short", "mid", "long"}},
+	}
+	Convey("Rank should order paths by preference", t, func() {
+		for _, tc := range testCases {
+			Convey(tc.Name, func() {
+				var expected []*spathmeta.AppPath
+				for _, name := range tc.Expected {
+					expected = append(expected, paths[name])
+				}
+				SoMsg("ranked", Rank(aps, tc.Ranker), ShouldResemble, expected)
+			})
+		}
+	})
+}
+
+func TestSyncPathsGetAppPath(t *testing.T) {
+	Convey("GetAppPath should fall back to the most preferred path", t, func() {
+		aps, paths := newTestAPS()
+		sp := NewSyncPaths()
+		SoMsg("empty", sp.Load().GetAppPath(""), ShouldBeNil)
+		sp.update(aps)
+		SoMsg("pref", sp.Load().GetAppPath(paths["mid"].Key()), ShouldEqual, paths["mid"])
+		SoMsg("fallback", sp.Load().GetAppPath("foo"), ShouldEqual, paths["short"])
+		sp.setRanker(RankMTU)
+		SoMsg("fallback mtu", sp.Load().GetAppPath("foo"), ShouldEqual, paths["long"])
+	})
+}
+
+func TestSetRanker(t *testing.T) {
+	Convey("Watched paths should be reordered when the ranker changes", t, func() {
+		g := graph.NewDefaultGraph()
+		g.AddLink("1-ff00:0:133", 101902, "1-ff00:0:132", 191002, false)
+		pm := NewPR(t, g, 500, 500, 1000)
+		srcIA := xtest.MustParseIA("1-ff00:0:133")
+		dstIA := xtest.MustParseIA("1-ff00:0:131")
+
+		sp, err := pm.Watch(srcIA, dstIA)
+		SoMsg("err", err, ShouldBeNil)
+		ranked := sp.Load().Ranked
+		SoMsg("len", len(ranked), ShouldEqual, 2)
+		SoMsg("query", pm.QueryRanked(srcIA, dstIA), ShouldResemble, ranked)
+
+		// Reverse the default order
+		pm.SetRanker(RankerFunc(func(a, b *spathmeta.AppPath) bool {
+			return a.Key() > b.Key()
+		}))
+		SoMsg("reversed", sp.Load().Ranked, ShouldResemble,
+			[]*spathmeta.AppPath{ranked[1], ranked[0]})
+		SoMsg("query reversed", pm.QueryRanked(srcIA, dstIA), ShouldResemble,
+			[]*spathmeta.AppPath{ranked[1], ranked[0]})
+		SoMsg("preferred", pm.QueryPreferred(srcIA, dstIA, ranked[0].Key()), ShouldResemble,
+			ranked[0])
+		SoMsg("preferred fallback", pm.QueryPreferred(srcIA, dstIA, "foo"), ShouldResemble,
+			ranked[1])
+	})
+}
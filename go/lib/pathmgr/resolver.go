@@ -28,8 +28,6 @@ import (
 type resolver struct {
 	sciondService sciond.Service
 	sciondConn    sciond.Connector
-	// Wait time after a failed (error or empty) path lookup (for periodic lookups)
-	errorRefire time.Duration
 	// Wait time after a successful path lookup (for periodic lookups)
 	normalRefire time.Duration
 	// information about paths
@@ -51,7 +49,9 @@ func (r *resolver) run() {
 			// Unblock the waiting client
 			close(request.done)
 		case reqMonitor:
-			r.cache.update(request.src, request.dst, aps)
+			// If no paths were found, retry once the negative cache entry
+			// expires
+			failed, retry := r.cache.update(request.src, request.dst, aps)
 			// If someone's waiting for this request to be done, unblock them
 			if request.done != nil {
 				close(request.done)
@@ -60,11 +60,9 @@ func (r *resolver) run() {
 			if r.cache.isWatched(request.src, request.dst) {
 				// Create new request, without done channel
 				request.done = nil
-				var wait time.Duration
-				if len(aps) == 0 {
-					wait = r.errorRefire
-				} else {
-					wait = r.normalRefire
+				wait := r.normalRefire
+				if failed {
+					wait = retry
 				}
 				// Make a copy of loop var for closure.
 				req := request
@@ -19,6 +19,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/lib/spath/spathmeta"
 )
 
@@ -28,23 +29,52 @@ import (
 // change the value of the reference within a SyncPaths to a different slice
 // containing new paths. Calling code should reload the reference often to make
 // sure the paths are fresh. Timestamp() can be called to get the time of the
-// last write.
+// last write. Instead of polling, callers can also Subscribe to be notified
+// whenever paths are added or removed, including removals due to revocations.
 //
 // A SyncPaths must never be copied.
 type SyncPaths struct {
 	value atomic.Value
 	// Used to avoid races between multiple writers
 	mutex sync.Mutex
+	// Ranker used to order the paths, protected by mutex. If nil,
+	// DefaultRanker is used.
+	ranker Ranker
+	// Health of the paths, protected by mutex
+	health map[spathmeta.PathKey]PathHealth
+	// Subscriptions to path changes, protected by mutex
+	subs map[*Subscription]struct{}
+	// Set once sp is no longer updated, protected by mutex
+	closed bool
 }
 
 // SyncPathsData is the atomic value inside a SyncPaths object. It provides a
-// snapshot of a SyncPaths object. Callers must not change APS.
+// snapshot of a SyncPaths object. Callers must not change APS or Ranked.
 type SyncPathsData struct {
-	APS         spathmeta.AppPathSet
+	APS spathmeta.AppPathSet
+	// Ranked contains the paths in APS in order of preference. Paths that are
+	// down are always ranked last.
+	Ranked []*spathmeta.AppPath
+	// Health contains the probing results of paths, if a Prober is running.
+	// It may also contain paths that are not in APS.
+	Health      map[spathmeta.PathKey]PathHealth
 	ModifyTime  time.Time
 	RefreshTime time.Time
 }
 
+// GetAppPath returns the path with key pref, unless it is down. Otherwise,
+// the most preferred path is returned. If there are no paths, nil is
+// returned.
+func (data *SyncPathsData) GetAppPath(pref spathmeta.PathKey) *spathmeta.AppPath {
+	if ap, ok := data.APS[pref]; ok && !data.Health[pref].Down {
+		return ap
+	}
+	if len(data.Ranked) == 0 {
+		return nil
+	}
+	return data.Ranked[0]
+}
+
 // NewSyncPaths creates a new SyncPaths object and sets the timestamp to
 // current time.  A newly created SyncPaths contains a nil spathmeta.AppPathSet.
 func NewSyncPaths() *SyncPaths {
@@ -53,6 +83,7 @@ func NewSyncPaths() *SyncPaths {
 	sp.value.Store(
 		&SyncPathsData{
 			APS:         make(spathmeta.AppPathSet),
+			Ranked:      []*spathmeta.AppPath{},
 			ModifyTime:  now,
 			RefreshTime: now,
 		},
@@ -68,7 +99,7 @@ func NewSyncPaths() *SyncPaths {
 func (sp *SyncPaths) update(newAPS spathmeta.AppPathSet) {
 	sp.mutex.Lock()
 	defer sp.mutex.Unlock()
-	value := sp.value.Load().(*SyncPathsData)
+	value := *sp.value.Load().(*SyncPathsData)
 	value.RefreshTime = time.Now()
 	toAdd := setSubtract(newAPS, value.APS)
 	toRemove := setSubtract(value.APS, newAPS)
@@ -76,7 +107,51 @@ func (sp *SyncPaths) update(newAPS spathmeta.AppPathSet) {
 		value.ModifyTime = value.RefreshTime
 	}
 	value.APS = newAPS
-	sp.value.Store(value)
+	value.Health = sp.health
+	value.Ranked = rankHealth(newAPS, sp.ranker, sp.health)
+	sp.value.Store(&value)
+	if len(toAdd) > 0 || len(toRemove) > 0 {
+		change := &PathsChange{Added: toAdd, Removed: toRemove, Data: &value}
+		for s := range sp.subs {
+			s.push(change)
+		}
+	}
+}
+
+// setRanker changes the ranker of sp to r, and reorders the paths.
+func (sp *SyncPaths) setRanker(r Ranker) {
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+	sp.ranker = r
+	value := *sp.value.Load().(*SyncPathsData)
+	value.Ranked = rankHealth(value.APS, r, sp.health)
+	sp.value.Store(&value)
+}
+
+// setHealth changes the health of the paths in sp to health, and reorders the
+// paths.
+func (sp *SyncPaths) setHealth(health map[spathmeta.PathKey]PathHealth) {
+	sp.mutex.Lock()
+	defer sp.mutex.Unlock()
+	sp.health = health
+	value := *sp.value.Load().(*SyncPathsData)
+	value.Health = health
+	value.Ranked = rankHealth(value.APS, sp.ranker, health)
+	sp.value.Store(&value)
+}
+
+// rankHealth is similar to Rank, but always ranks paths that are down last.
+func rankHealth(aps spathmeta.AppPathSet, r Ranker,
+	health map[spathmeta.PathKey]PathHealth) []*spathmeta.AppPath {
+	if r == nil {
+		r = DefaultRanker
+	}
+	if len(health) == 0 {
+		return Rank(aps, r)
+	}
+	return Rank(aps, Chain(RankerFunc(func(a, b *spathmeta.AppPath) bool {
+		return !health[a.Key()].Down && health[b.Key()].Down
+	}), r))
 }
 
 // Load returns a SyncPathsData snapshot of the data within sp.
@@ -93,3 +168,124 @@ func setSubtract(x, y spathmeta.AppPathSet) spathmeta.AppPathSet {
 	}
 	return result
 }
+
+// PathsChange describes the paths that were added to or removed from a
+// SyncPaths object. Callers must not change its contents.
+type PathsChange struct {
+	Added   spathmeta.AppPathSet
+	Removed spathmeta.AppPathSet
+	// Data is a snapshot of the SyncPaths object after the change
+	Data *SyncPathsData
+}
+
+// Subscribe registers f to be called on every change of the paths in sp. The
+// calls are made in order of the changes, from a goroutine dedicated to the
+// subscription. Changes that happen while f runs are queued, so a slow f
+// never blocks the path manager.
+//
+// Subscriptions end when they are unsubscribed, or when sp is no longer
+// updated because its watch was removed (e.g., via PR.Unwatch). Subscribing to
+// such a SyncPaths returns a subscription that never calls f.
+func (sp *SyncPaths) Subscribe(f func(*PathsChange)) *Subscription {
+	s := &Subscription{
+		sp:        sp,
+		f:         f,
+		signal:    make(chan struct{}, 1),
+		closeChan: make(chan struct{}),
+	}
+	sp.mutex.Lock()
+	if sp.closed {
+		sp.mutex.Unlock()
+		s.close()
+		return s
+	}
+	if sp.subs == nil {
+		sp.subs = make(map[*Subscription]struct{})
+	}
+	sp.subs[s] = struct{}{}
+	sp.mutex.Unlock()
+	go s.run()
+	return s
+}
+
+// close (internal) ends all subscriptions of sp. It is called when sp is no
+// longer updated.
+func (sp *SyncPaths) close() {
+	sp.mutex.Lock()
+	subs := sp.subs
+	sp.subs = nil
+	sp.closed = true
+	sp.mutex.Unlock()
+	for s := range subs {
+		s.close()
+	}
+}
+
+// Subscription is a registration for path changes of a SyncPaths object.
+type Subscription struct {
+	sp    *SyncPaths
+	f     func(*PathsChange)
+	mutex sync.Mutex
+	// Changes not yet passed to f, protected by mutex
+	queue []*PathsChange
+	// Notified when changes are added to queue
+	signal    chan struct{}
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+// Unsubscribe stops the delivery of changes. Queued changes are discarded.
+// Unsubscribe can be called multiple times.
+func (s *Subscription) Unsubscribe() {
+	s.sp.mutex.Lock()
+	delete(s.sp.subs, s)
+	s.sp.mutex.Unlock()
+	s.close()
+}
+
+// close (internal) stops the delivery of changes and discards queued changes.
+func (s *Subscription) close() {
+	s.closeOnce.Do(func() { close(s.closeChan) })
+	s.mutex.Lock()
+	s.queue = nil
+	s.mutex.Unlock()
+}
+
+// push (internal) queues a change for delivery.
+func (s *Subscription) push(change *PathsChange) {
+	s.mutex.Lock()
+	s.queue = append(s.queue, change)
+	s.mutex.Unlock()
+	select {
+	case s.signal <- struct{}{}:
+	default:
+	}
+}
+
+// run (internal) delivers queued changes until the subscription is closed.
+func (s *Subscription) run() {
+	defer log.LogPanicAndExit()
+	for {
+		select {
+		case <-s.closeChan:
+			return
+		case <-s.signal:
+		}
+		for {
+			s.mutex.Lock()
+			if len(s.queue) == 0 {
+				s.mutex.Unlock()
+				break
+			}
+			change := s.queue[0]
+			s.queue = s.queue[1:]
+			s.mutex.Unlock()
+			select {
+			case <-s.closeChan:
+				return
+			default:
+			}
+			s.f(change)
+		}
+	}
+}
@@ -21,6 +21,8 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 
 	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/lib/xtest/graph"
 )
 
 func TestSyncPathsTimestamp(t *testing.T) {
@@ -46,3 +48,91 @@ func TestSyncPathsTimestamp(t *testing.T) {
 		})
 	})
 }
+
+func TestSyncPathsSubscribe(t *testing.T) {
+	Convey("Subscribe to a SyncPaths object", t, func() {
+		aps, paths := newTestAPS()
+		sp := NewSyncPaths()
+		changes := make(chan *PathsChange, 10)
+		sub := sp.Subscribe(func(change *PathsChange) {
+			changes <- change
+		})
+		sp.update(aps)
+		change := <-changes
+		SoMsg("added", change.Added, ShouldResemble, aps)
+		SoMsg("removed", len(change.Removed), ShouldEqual, 0)
+		SoMsg("data", change.Data.APS, ShouldResemble, aps)
+
+		Convey("Updates without changes are not delivered", func() {
+			sp.update(aps)
+			sp.update(spathmeta.AppPathSet{
+				paths["short"].Key(): paths["short"],
+				paths["long"].Key():  paths["long"],
+			})
+			change := <-changes
+			SoMsg("added", len(change.Added), ShouldEqual, 0)
+			SoMsg("removed", change.Removed, ShouldResemble,
+				spathmeta.AppPathSet{paths["mid"].Key(): paths["mid"]})
+		})
+		Convey("No changes are delivered after unsubscribing", func() {
+			sub.Unsubscribe()
+			sub.Unsubscribe()
+			sp.update(nil)
+			select {
+			case <-changes:
+				t.Fatalf("Unexpected change after unsubscribing")
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	})
+}
+
+func TestSyncPathsSubscribeRevoke(t *testing.T) {
+	Convey("Revocations are delivered to subscribers", t, func() {
+		g := graph.NewDefaultGraph()
+		pm := NewPR(t, g, 60, 60, 60)
+		src := xtest.MustParseIA("1-ff00:0:122")
+		dst := xtest.MustParseIA("2-ff00:0:220")
+		sp, err := pm.Watch(src, dst)
+		xtest.FailOnErr(t, err)
+		changes := make(chan *PathsChange, 10)
+		sp.Subscribe(func(change *PathsChange) {
+			changes <- change
+		})
+		g.RemoveLink(1815)
+		pm.cache.revoke(uifidFromValues(src, 1815))
+		select {
+		case change := <-changes:
+			apsCheckPaths("removed", change.Removed,
+				"[1-ff00:0:122#1815 1-ff00:0:121#1518 1-ff00:0:121#1512 "+
+					"1-ff00:0:120#1215 1-ff00:0:120#1222 2-ff00:0:220#2212]")
+			apsCheckPaths("remaining", change.Data.APS)
+		case <-time.After(time.Second):
+			t.Fatalf("No change delivered after revocation")
+		}
+	})
+}
+
+func TestSyncPathsSubscribeUnwatch(t *testing.T) {
+	Convey("Subscriptions end when the watch is removed", t, func() {
+		g := graph.NewDefaultGraph()
+		pm := NewPR(t, g, 60, 60, 60)
+		src := xtest.MustParseIA("1-ff00:0:122")
+		dst := xtest.MustParseIA("2-ff00:0:220")
+		sp, err := pm.Watch(src, dst)
+		xtest.FailOnErr(t, err)
+		sub := sp.Subscribe(func(change *PathsChange) {})
+		xtest.FailOnErr(t, pm.Unwatch(src, dst))
+		select {
+		case <-sub.closeChan:
+		case <-time.After(time.Second):
+			t.Fatalf("Subscription not closed after unwatch")
+		}
+		late := sp.Subscribe(func(change *PathsChange) {})
+		select {
+		case <-late.closeChan:
+		default:
+			t.Fatalf("Subscription to unwatched paths not closed")
+		}
+	})
+}
@@ -33,7 +33,8 @@ type Action interface {
 var _ Action = (*ActionFilterPaths)(nil)
 
 // ActionFilterPaths filters paths according to the embedded Cond object.
-// CondAnyOf, CondAllOf, CondNot and CondPathPredicate conditions can be
+// CondAnyOf, CondAllOf, CondNot, CondPathPredicate and the path conditions in
+// cond_path.go (deny lists, sequences, hop count, MTU and expiry) can be
 // combined to implement complex path selection policies.
 type ActionFilterPaths struct {
 	Cond Cond
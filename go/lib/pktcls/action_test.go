@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"strings"
 	"testing"
+	"time"
 
 	. "github.com/smartystreets/goconvey/convey"
 
@@ -174,6 +175,22 @@ func TestActionMap(t *testing.T) {
 			FileName: "act_10",
 			Actions:  nil,
 		},
+		{
+			Name:     "CondAllOf(path properties)",
+			FileName: "act_11",
+			Actions: map[string]Action{
+				"A": &ActionFilterPaths{
+					Name: "A",
+					Cond: CondAllOf{
+						mustCondPathDenyList(t, "3-0#0,1-ff00:0:110#5"),
+						mustCondPathSequence(t, "1-ff00:0:110#0 1-0#0* 2-0#0{2,4}"),
+						&CondPathMaxHops{MaxHops: 5},
+						&CondPathMinMTU{MTU: 1280},
+						&CondPathMinTTL{TTL: 5 * time.Minute},
+					},
+				},
+			},
+		},
 	}
 
 	Convey("Test action map marshal/unmarshal", t, func() {
@@ -67,6 +67,39 @@ func TestClassMap(t *testing.T) {
 				),
 			},
 		},
+		{
+			Name:     "IPv6 and L4",
+			FileName: "class_3",
+			Classes: ClassMap{
+				"dns over IPv6": NewClass(
+					"dns over IPv6",
+					NewCondAllOf(
+						NewCondIPv6(&IPv6MatchDestination{
+							&net.IPNet{
+								IP:   net.ParseIP("2001:db8::"),
+								Mask: net.CIDRMask(32, 128),
+							},
+						}),
+						NewCondL4(&L4MatchProtocol{17}),
+						NewCondL4(&L4MatchDstPort{53, 53}),
+					),
+				),
+				"ipv6 bulk": NewClass(
+					"ipv6 bulk",
+					NewCondAnyOf(
+						NewCondIPv6(&IPv6MatchTrafficClass{0x20}),
+						NewCondIPv6(&IPv6MatchFlowLabel{0xbeef}),
+						NewCondIPv6(&IPv6MatchSource{
+							&net.IPNet{
+								IP:   net.ParseIP("fd00::"),
+								Mask: net.CIDRMask(8, 128),
+							},
+						}),
+						NewCondL4(&L4MatchSrcPort{1024, 65535}),
+					),
+				),
+			},
+		},
 		{
 			Name:     "nil ClassMap stays nil",
 			FileName: "class_2",
@@ -217,6 +250,34 @@ func TestBadJSON(t *testing.T) {
 			},
 			"Name": "Unable to parse source operand string"
 		}
+		`, `
+		{
+			"CondIPv6": {
+				"IPv6MatchFlowLabel": {
+					"FlowLabel": "0x100000"
+				}
+			},
+			"Name": "Flow label out of range"
+		}
+		`, `
+		{
+			"CondL4": {
+				"MatchDstPort": {
+					"Min": "2000",
+					"Max": "1000"
+				}
+			},
+			"Name": "Inverted port range"
+		}
+		`, `
+		{
+			"CondL4": {
+				"MatchSource": {
+					"Net": "10.0.0.0/8"
+				}
+			},
+			"Name": "IPv4 predicate in L4 condition"
+		}
 	`}
 	Convey("Marshaling bad JSON should return errors", t, func() {
 		for i, tc := range testCases {
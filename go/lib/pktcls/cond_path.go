@@ -0,0 +1,173 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file contains conditions on path properties, designed for use in
+// conditions for ActionFilterPaths. All of them expect the argument to Eval to
+// be a *sciond.PathReplyEntry.
+
+package pktcls
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+)
+
+var _ Cond = (*CondPathDenyList)(nil)
+
+// CondPathDenyList returns true if the path does not traverse any of the
+// interfaces in the embedded deny list DL.
+type CondPathDenyList struct {
+	DL *spathmeta.PathDenyList
+}
+
+func NewCondPathDenyList(dl *spathmeta.PathDenyList) *CondPathDenyList {
+	return &CondPathDenyList{DL: dl}
+}
+
+func (c *CondPathDenyList) Eval(v interface{}) bool {
+	return c.DL.Eval(v.(*sciond.PathReplyEntry))
+}
+
+func (c *CondPathDenyList) Type() string {
+	return TypeCondPathDenyList
+}
+
+var _ Cond = (*CondPathSequence)(nil)
+
+// CondPathSequence returns true if the interfaces of the path match the
+// embedded sequence PS.
+type CondPathSequence struct {
+	PS *spathmeta.PathSequence
+}
+
+func NewCondPathSequence(ps *spathmeta.PathSequence) *CondPathSequence {
+	return &CondPathSequence{PS: ps}
+}
+
+func (c *CondPathSequence) Eval(v interface{}) bool {
+	return c.PS.Eval(v.(*sciond.PathReplyEntry))
+}
+
+func (c *CondPathSequence) Type() string {
+	return TypeCondPathSequence
+}
+
+var _ Cond = (*CondPathMaxHops)(nil)
+
+// CondPathMaxHops returns true if the path traverses at most MaxHops
+// inter-AS links.
+type CondPathMaxHops struct {
+	MaxHops uint8
+}
+
+func (c *CondPathMaxHops) Eval(v interface{}) bool {
+	path := v.(*sciond.PathReplyEntry)
+	return len(path.Path.Interfaces)/2 <= int(c.MaxHops)
+}
+
+func (c *CondPathMaxHops) Type() string {
+	return TypeCondPathMaxHops
+}
+
+func (c *CondPathMaxHops) MarshalJSON() ([]byte, error) {
+	return json.Marshal(
+		jsonContainer{
+			"MaxHops": strconv.Itoa(int(c.MaxHops)),
+		},
+	)
+}
+
+func (c *CondPathMaxHops) UnmarshalJSON(b []byte) error {
+	i, err := unmarshalUintField(b, TypeCondPathMaxHops, "MaxHops", 8)
+	if err != nil {
+		return err
+	}
+	c.MaxHops = uint8(i)
+	return nil
+}
+
+var _ Cond = (*CondPathMinMTU)(nil)
+
+// CondPathMinMTU returns true if the MTU of the path is at least MTU.
+type CondPathMinMTU struct {
+	MTU uint16
+}
+
+func (c *CondPathMinMTU) Eval(v interface{}) bool {
+	path := v.(*sciond.PathReplyEntry)
+	return path.Path.Mtu >= c.MTU
+}
+
+func (c *CondPathMinMTU) Type() string {
+	return TypeCondPathMinMTU
+}
+
+func (c *CondPathMinMTU) MarshalJSON() ([]byte, error) {
+	return json.Marshal(
+		jsonContainer{
+			"MTU": strconv.Itoa(int(c.MTU)),
+		},
+	)
+}
+
+func (c *CondPathMinMTU) UnmarshalJSON(b []byte) error {
+	i, err := unmarshalUintField(b, TypeCondPathMinMTU, "MTU", 16)
+	if err != nil {
+		return err
+	}
+	c.MTU = uint16(i)
+	return nil
+}
+
+var _ Cond = (*CondPathMinTTL)(nil)
+
+// CondPathMinTTL returns true if the path is valid for at least TTL at the
+// time of evaluation.
+type CondPathMinTTL struct {
+	TTL time.Duration
+}
+
+func (c *CondPathMinTTL) Eval(v interface{}) bool {
+	path := v.(*sciond.PathReplyEntry)
+	return !path.Path.Expiry().Before(time.Now().Add(c.TTL))
+}
+
+func (c *CondPathMinTTL) Type() string {
+	return TypeCondPathMinTTL
+}
+
+func (c *CondPathMinTTL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(
+		jsonContainer{
+			"TTL": c.TTL.String(),
+		},
+	)
+}
+
+func (c *CondPathMinTTL) UnmarshalJSON(b []byte) error {
+	s, err := unmarshalStringField(b, TypeCondPathMinTTL, "TTL")
+	if err != nil {
+		return err
+	}
+	c.TTL, err = time.ParseDuration(s)
+	if err != nil {
+		return common.NewBasicError("Unable to parse TTL operand", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pktcls
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func mustCondPathDenyList(t *testing.T, str string) *CondPathDenyList {
+	t.Helper()
+
+	dl, err := spathmeta.NewPathDenyList(str)
+	xtest.FailOnErr(t, err)
+	return NewCondPathDenyList(dl)
+}
+
+func mustCondPathSequence(t *testing.T, str string) *CondPathSequence {
+	t.Helper()
+
+	ps, err := spathmeta.NewPathSequence(str)
+	xtest.FailOnErr(t, err)
+	return NewCondPathSequence(ps)
+}
+
+func TestPathConds(t *testing.T) {
+	ia110 := xtest.MustParseIA("1-ff00:0:110")
+	ia310 := xtest.MustParseIA("3-ff00:0:310")
+	entry := &sciond.PathReplyEntry{
+		Path: &sciond.FwdPathMeta{
+			Mtu: 1400,
+			Interfaces: []sciond.PathInterface{
+				{RawIsdas: ia110.IAInt(), IfID: 1},
+				{RawIsdas: ia310.IAInt(), IfID: 2},
+				{RawIsdas: ia310.IAInt(), IfID: 3},
+				{RawIsdas: ia110.IAInt(), IfID: 4},
+			},
+			ExpTime: uint32(time.Now().Add(time.Hour).Unix()),
+		},
+	}
+	testCases := []struct {
+		Name     string
+		Cond     Cond
+		Expected bool
+	}{
+		{"deny ISD 3", mustCondPathDenyList(t, "3-0#0"), false},
+		{"deny ISD 2", mustCondPathDenyList(t, "2-0#0"), true},
+		{"sequence", mustCondPathSequence(t, "1-0#0 3-0#0{2} 1-0#0"), true},
+		{"max 2 hops", &CondPathMaxHops{MaxHops: 2}, true},
+		{"max 1 hop", &CondPathMaxHops{MaxHops: 1}, false},
+		{"min MTU 1280", &CondPathMinMTU{MTU: 1280}, true},
+		{"min MTU 1500", &CondPathMinMTU{MTU: 1500}, false},
+		{"min TTL 30m", &CondPathMinTTL{TTL: 30 * time.Minute}, true},
+		{"min TTL 2h", &CondPathMinTTL{TTL: 2 * time.Hour}, false},
+		{
+			"MTU and not ISD 3",
+			NewCondAllOf(
+				&CondPathMinMTU{MTU: 1280},
+				NewCondNot(mustCondPathPredicate(t, "3-0#0")),
+			),
+			false,
+		},
+	}
+	Convey("Path conditions should evaluate path properties", t, func() {
+		for _, tc := range testCases {
+			Convey(tc.Name, func() {
+				SoMsg("eval", tc.Cond.Eval(entry), ShouldEqual, tc.Expected)
+			})
+		}
+	})
+}
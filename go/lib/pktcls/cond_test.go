@@ -155,6 +155,126 @@ func TestIPCond(t *testing.T) {
 	})
 }
 
+func TestIPv6Cond(t *testing.T) {
+	pkt := newTestPacketLayers(
+		&layers.IPv6{
+			Version:      6,
+			TrafficClass: 0xb8,
+			FlowLabel:    0x12345,
+			NextHeader:   layers.IPProtocolUDP,
+			HopLimit:     64,
+			SrcIP:        net.ParseIP("2001:db8:a::1"),
+			DstIP:        net.ParseIP("2001:db8:b::2"),
+		},
+		&layers.UDP{SrcPort: 40000, DstPort: 53},
+		gopacket.Payload([]byte{1, 2, 3, 4}),
+	)
+	_, dstNet, _ := net.ParseCIDR("2001:db8:b::/48")
+	testCases := []struct {
+		Name    string
+		Cond    Cond
+		ExpEval bool
+	}{
+		{
+			Name:    "Match IPv6 destination",
+			Cond:    NewCondIPv6(&IPv6MatchDestination{Net: dstNet}),
+			ExpEval: true,
+		},
+		{
+			Name:    "Mismatch IPv6 source",
+			Cond:    NewCondIPv6(&IPv6MatchSource{Net: dstNet}),
+			ExpEval: false,
+		},
+		{
+			Name: "Match traffic class and flow label",
+			Cond: NewCondAllOf(
+				NewCondIPv6(&IPv6MatchTrafficClass{TrafficClass: 0xb8}),
+				NewCondIPv6(&IPv6MatchFlowLabel{FlowLabel: 0x12345}),
+			),
+			ExpEval: true,
+		},
+		{
+			Name:    "IPv4 condition on IPv6 packet",
+			Cond:    NewCondIPv4(&IPv4MatchToS{TOS: 0}),
+			ExpEval: false,
+		},
+	}
+
+	Convey("TestIPv6Cond", t, func() {
+		for _, tc := range testCases {
+			Convey(tc.Name, func() {
+				SoMsg("eval", tc.Cond.Eval(pkt), ShouldEqual, tc.ExpEval)
+			})
+		}
+	})
+}
+
+func TestL4Cond(t *testing.T) {
+	ipv4 := &layers.IPv4{
+		Version:  4,
+		IHL:      5,
+		TTL:      64,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.IP{192, 168, 1, 1},
+		DstIP:    net.IP{10, 0, 0, 2},
+	}
+	tcpPkt := newTestPacketLayers(ipv4, &layers.TCP{SrcPort: 50000, DstPort: 443},
+		gopacket.Payload([]byte{1, 2, 3, 4}))
+	ipv6 := &layers.IPv6{
+		Version:    6,
+		NextHeader: layers.IPProtocolICMPv6,
+		HopLimit:   64,
+		SrcIP:      net.ParseIP("2001:db8:a::1"),
+		DstIP:      net.ParseIP("2001:db8:b::2"),
+	}
+	icmpPkt := newTestPacketLayers(ipv6, gopacket.Payload([]byte{1, 2, 3, 4}))
+	testCases := []struct {
+		Name    string
+		Cond    Cond
+		Packet  *Packet
+		ExpEval bool
+	}{
+		{
+			Name:    "Match TCP protocol",
+			Cond:    NewCondL4(&L4MatchProtocol{Protocol: 6}),
+			Packet:  tcpPkt,
+			ExpEval: true,
+		},
+		{
+			Name:    "Match TCP destination port range",
+			Cond:    NewCondL4(&L4MatchDstPort{Min: 443, Max: 443}),
+			Packet:  tcpPkt,
+			ExpEval: true,
+		},
+		{
+			Name:    "Mismatch TCP source port range",
+			Cond:    NewCondL4(&L4MatchSrcPort{Min: 1, Max: 1023}),
+			Packet:  tcpPkt,
+			ExpEval: false,
+		},
+		{
+			Name:    "Match ICMPv6 protocol",
+			Cond:    NewCondL4(&L4MatchProtocol{Protocol: 58}),
+			Packet:  icmpPkt,
+			ExpEval: true,
+		},
+		{
+			Name:    "Port range never matches ICMPv6",
+			Cond:    NewCondL4(&L4MatchDstPort{Min: 0, Max: 65535}),
+			Packet:  icmpPkt,
+			ExpEval: false,
+		},
+	}
+
+	Convey("TestL4Cond", t, func() {
+		for _, tc := range testCases {
+			Convey(tc.Name, func() {
+				SoMsg("eval", tc.Cond.Eval(tc.Packet), ShouldEqual, tc.ExpEval)
+			})
+		}
+	})
+}
+
 func newTestPacket(ipv4 *layers.IPv4, pld []byte) *Packet {
 	buf := gopacket.NewSerializeBuffer()
 	gopacket.SerializeLayers(
@@ -165,3 +285,9 @@ func newTestPacket(ipv4 *layers.IPv4, pld []byte) *Packet {
 	)
 	return NewPacket(buf.Bytes())
 }
+
+func newTestPacketLayers(l ...gopacket.SerializableLayer) *Packet {
+	buf := gopacket.NewSerializeBuffer()
+	gopacket.SerializeLayers(buf, gopacket.SerializeOptions{FixLengths: true}, l...)
+	return NewPacket(buf.Bytes())
+}
@@ -19,14 +19,18 @@
 // true for a ClsPkt, that packet is considered to be part of that class.
 //
 // The following conditions are supported:
-// AnyOf, AllOf, Boolean true, Boolean false and IPv4. AnyOf returns true if at
-// least one subcondition returns true. AllOf returns true if all subconditions
-// return true.  AllOf or AnyOf without subconditions return true. Boolean
-// conditions always return their internal value. IPv4 conditions include
-// predicates that compare the analyzed packet to preset values. Supported IPv4
-// conditions currently include destination network match, source network match
-// and ToS/DSCP fields match. Multiple predicates can be checked by enumerating
-// them under AllOf or AnyOf.
+// AnyOf, AllOf, Boolean true, Boolean false, IPv4, IPv6 and L4. AnyOf returns
+// true if at least one subcondition returns true. AllOf returns true if all
+// subconditions return true.  AllOf or AnyOf without subconditions return
+// true. Boolean conditions always return their internal value. IPv4, IPv6 and
+// L4 conditions include predicates that compare the analyzed packet to preset
+// values. Supported IPv4 conditions currently include destination network
+// match, source network match and ToS/DSCP fields match. Supported IPv6
+// conditions include destination network match, source network match and
+// Traffic Class/Flow Label fields match. Supported L4 conditions include
+// transport protocol match and TCP/UDP source and destination port range
+// match. Multiple predicates can be checked by enumerating them under AllOf or
+// AnyOf.
 //
 // Actions are marshalable objects that describe a process. Currently, the only
 // supported actions are Path Filters (ActionFilterPaths), which are containers
@@ -35,6 +39,10 @@
 // Marshalable policies can be implemented by external code by mapping Cond
 // items to Action items.
 //
+// Conditions without path predicates can also be written in a compact text
+// syntax, e.g., all(src=10.0.0.0/8, not(dscp=0x2e)). See ParseCond and
+// FormatCond.
+//
 // Package class supports JSON marshaling and unmarshaling of classes and
 // actions.  Due to the custom formatting of the JSON output, marshaling must
 // be done by first adding the classes and actions to a ClassMap or ActionMap,
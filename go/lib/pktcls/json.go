@@ -40,17 +40,31 @@ type Typer interface {
 // concrete type is unmarshaled.
 
 const (
-	TypeCondAllOf            = "CondAllOf"
-	TypeCondAnyOf            = "CondAnyOf"
-	TypeCondNot              = "CondNot"
-	TypeCondBool             = "CondBool"
-	TypeCondIPv4             = "CondIPv4"
-	TypeCondPathPredicate    = "CondPathPredicate"
-	TypeActionFilterPaths    = "ActionFilterPaths"
-	TypeIPv4MatchSource      = "MatchSource"
-	TypeIPv4MatchDestination = "MatchDestination"
-	TypeIPv4MatchToS         = "MatchToS"
-	TypeIPv4MatchDSCP        = "MatchDSCP"
+	TypeCondAllOf             = "CondAllOf"
+	TypeCondAnyOf             = "CondAnyOf"
+	TypeCondNot               = "CondNot"
+	TypeCondBool              = "CondBool"
+	TypeCondIPv4              = "CondIPv4"
+	TypeCondIPv6              = "CondIPv6"
+	TypeCondL4                = "CondL4"
+	TypeCondPathPredicate     = "CondPathPredicate"
+	TypeCondPathDenyList      = "CondPathDenyList"
+	TypeCondPathSequence      = "CondPathSequence"
+	TypeCondPathMaxHops       = "CondPathMaxHops"
+	TypeCondPathMinMTU        = "CondPathMinMTU"
+	TypeCondPathMinTTL        = "CondPathMinTTL"
+	TypeActionFilterPaths     = "ActionFilterPaths"
+	TypeIPv4MatchSource       = "MatchSource"
+	TypeIPv4MatchDestination  = "MatchDestination"
+	TypeIPv4MatchToS          = "MatchToS"
+	TypeIPv4MatchDSCP         = "MatchDSCP"
+	TypeIPv6MatchSource       = "IPv6MatchSource"
+	TypeIPv6MatchDestination  = "IPv6MatchDestination"
+	TypeIPv6MatchTrafficClass = "IPv6MatchTrafficClass"
+	TypeIPv6MatchFlowLabel    = "IPv6MatchFlowLabel"
+	TypeL4MatchProtocol       = "MatchProtocol"
+	TypeL4MatchSrcPort        = "MatchSrcPort"
+	TypeL4MatchDstPort        = "MatchDstPort"
 )
 
 // generic container for marshaling custom data
@@ -97,10 +111,38 @@ func unmarshalInterface(b []byte) (Typer, error) {
 			var c CondIPv4
 			err := json.Unmarshal(*v, &c)
 			return &c, err
+		case TypeCondIPv6:
+			var c CondIPv6
+			err := json.Unmarshal(*v, &c)
+			return &c, err
+		case TypeCondL4:
+			var c CondL4
+			err := json.Unmarshal(*v, &c)
+			return &c, err
 		case TypeCondPathPredicate:
 			var c CondPathPredicate
 			err := json.Unmarshal(*v, &c)
 			return &c, err
+		case TypeCondPathDenyList:
+			var c CondPathDenyList
+			err := json.Unmarshal(*v, &c)
+			return &c, err
+		case TypeCondPathSequence:
+			var c CondPathSequence
+			err := json.Unmarshal(*v, &c)
+			return &c, err
+		case TypeCondPathMaxHops:
+			var c CondPathMaxHops
+			err := json.Unmarshal(*v, &c)
+			return &c, err
+		case TypeCondPathMinMTU:
+			var c CondPathMinMTU
+			err := json.Unmarshal(*v, &c)
+			return &c, err
+		case TypeCondPathMinTTL:
+			var c CondPathMinTTL
+			err := json.Unmarshal(*v, &c)
+			return &c, err
 		case TypeActionFilterPaths:
 			var a ActionFilterPaths
 			err := json.Unmarshal(*v, &a)
@@ -121,6 +163,34 @@ func unmarshalInterface(b []byte) (Typer, error) {
 			var p IPv4MatchDSCP
 			err := json.Unmarshal(*v, &p)
 			return &p, err
+		case TypeIPv6MatchSource:
+			var p IPv6MatchSource
+			err := json.Unmarshal(*v, &p)
+			return &p, err
+		case TypeIPv6MatchDestination:
+			var p IPv6MatchDestination
+			err := json.Unmarshal(*v, &p)
+			return &p, err
+		case TypeIPv6MatchTrafficClass:
+			var p IPv6MatchTrafficClass
+			err := json.Unmarshal(*v, &p)
+			return &p, err
+		case TypeIPv6MatchFlowLabel:
+			var p IPv6MatchFlowLabel
+			err := json.Unmarshal(*v, &p)
+			return &p, err
+		case TypeL4MatchProtocol:
+			var p L4MatchProtocol
+			err := json.Unmarshal(*v, &p)
+			return &p, err
+		case TypeL4MatchSrcPort:
+			var p L4MatchSrcPort
+			err := json.Unmarshal(*v, &p)
+			return &p, err
+		case TypeL4MatchDstPort:
+			var p L4MatchDstPort
+			err := json.Unmarshal(*v, &p)
+			return &p, err
 		default:
 			return nil, common.NewBasicError("Unknown type", nil, "type", k)
 		}
@@ -154,15 +224,41 @@ func unmarshalAction(b []byte) (Action, error) {
 	return a, nil
 }
 
-// unmarshal extracts an IPv4Predicate from a JSON encoding
-func unmarshalPredicate(b []byte) (IPv4Predicate, error) {
+// unmarshalIPv4Predicate extracts an IPv4Predicate from a JSON encoding
+func unmarshalIPv4Predicate(b []byte) (IPv4Predicate, error) {
 	t, err := unmarshalInterface(b)
 	if err != nil {
 		return nil, err
 	}
 	p, ok := t.(IPv4Predicate)
 	if !ok {
-		return nil, common.NewBasicError("Unable to extract Cond from interface", nil)
+		return nil, common.NewBasicError("Unable to extract IPv4Predicate from interface", nil)
+	}
+	return p, nil
+}
+
+// unmarshalIPv6Predicate extracts an IPv6Predicate from a JSON encoding
+func unmarshalIPv6Predicate(b []byte) (IPv6Predicate, error) {
+	t, err := unmarshalInterface(b)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := t.(IPv6Predicate)
+	if !ok {
+		return nil, common.NewBasicError("Unable to extract IPv6Predicate from interface", nil)
+	}
+	return p, nil
+}
+
+// unmarshalL4Predicate extracts an L4Predicate from a JSON encoding
+func unmarshalL4Predicate(b []byte) (L4Predicate, error) {
+	t, err := unmarshalInterface(b)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := t.(L4Predicate)
+	if !ok {
+		return nil, common.NewBasicError("Unable to extract L4Predicate from interface", nil)
 	}
 	return p, nil
 }
@@ -0,0 +1,187 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pktcls
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/scionproto/scion/go/lib/common"
+)
+
+// L4Header contains the transport layer information of a packet that L4
+// predicates are evaluated on.
+type L4Header struct {
+	// Protocol is the IPv4 protocol number or the IPv6 next header value
+	// following any extension headers.
+	Protocol layers.IPProtocol
+	// SrcPort and DstPort are only set for TCP and UDP packets.
+	SrcPort uint16
+	DstPort uint16
+}
+
+// newL4Header extracts the transport layer information from pkt. It returns
+// false if pkt does not contain an IP header.
+func newL4Header(pkt gopacket.Packet) (*L4Header, bool) {
+	h := &L4Header{}
+	found := false
+	for _, layer := range pkt.Layers() {
+		switch l := layer.(type) {
+		case *layers.IPv4:
+			h.Protocol, found = l.Protocol, true
+		case *layers.IPv6:
+			h.Protocol, found = l.NextHeader, true
+		case *layers.IPv6HopByHop:
+			h.Protocol = l.NextHeader
+		case *layers.IPv6Routing:
+			h.Protocol = l.NextHeader
+		case *layers.IPv6Fragment:
+			h.Protocol = l.NextHeader
+		case *layers.IPv6Destination:
+			h.Protocol = l.NextHeader
+		case *layers.TCP:
+			h.SrcPort, h.DstPort = uint16(l.SrcPort), uint16(l.DstPort)
+		case *layers.UDP:
+			h.SrcPort, h.DstPort = uint16(l.SrcPort), uint16(l.DstPort)
+		}
+	}
+	return h, found
+}
+
+// hasPorts returns true if the header belongs to a TCP or UDP packet.
+func (h *L4Header) hasPorts() bool {
+	return h.Protocol == layers.IPProtocolTCP || h.Protocol == layers.IPProtocolUDP
+}
+
+// L4Predicate describes a single test on the transport layer of a packet.
+type L4Predicate interface {
+	// Eval returns true if the transport layer matched the predicate
+	Eval(*L4Header) bool
+	Typer
+}
+
+var _ L4Predicate = (*L4MatchProtocol)(nil)
+
+// L4MatchProtocol checks whether the transport protocol matches.
+type L4MatchProtocol struct {
+	Protocol uint8
+}
+
+func (m *L4MatchProtocol) Type() string {
+	return TypeL4MatchProtocol
+}
+
+func (m *L4MatchProtocol) Eval(h *L4Header) bool {
+	return m.Protocol == uint8(h.Protocol)
+}
+
+func (m *L4MatchProtocol) MarshalJSON() ([]byte, error) {
+	return json.Marshal(
+		jsonContainer{
+			"Protocol": strconv.Itoa(int(m.Protocol)),
+		},
+	)
+}
+
+func (m *L4MatchProtocol) UnmarshalJSON(b []byte) error {
+	i, err := unmarshalUintField(b, TypeL4MatchProtocol, "Protocol", 8)
+	if err != nil {
+		return err
+	}
+	m.Protocol = uint8(i)
+	return nil
+}
+
+var _ L4Predicate = (*L4MatchSrcPort)(nil)
+
+// L4MatchSrcPort checks whether the TCP or UDP source port is in the range
+// [Min, Max]. Packets of other protocols never match.
+type L4MatchSrcPort struct {
+	Min uint16
+	Max uint16
+}
+
+func (m *L4MatchSrcPort) Type() string {
+	return TypeL4MatchSrcPort
+}
+
+func (m *L4MatchSrcPort) Eval(h *L4Header) bool {
+	return h.hasPorts() && h.SrcPort >= m.Min && h.SrcPort <= m.Max
+}
+
+func (m *L4MatchSrcPort) MarshalJSON() ([]byte, error) {
+	return marshalPortRange(m.Min, m.Max)
+}
+
+func (m *L4MatchSrcPort) UnmarshalJSON(b []byte) error {
+	var err error
+	m.Min, m.Max, err = unmarshalPortRange(b, TypeL4MatchSrcPort)
+	return err
+}
+
+var _ L4Predicate = (*L4MatchDstPort)(nil)
+
+// L4MatchDstPort checks whether the TCP or UDP destination port is in the
+// range [Min, Max]. Packets of other protocols never match.
+type L4MatchDstPort struct {
+	Min uint16
+	Max uint16
+}
+
+func (m *L4MatchDstPort) Type() string {
+	return TypeL4MatchDstPort
+}
+
+func (m *L4MatchDstPort) Eval(h *L4Header) bool {
+	return h.hasPorts() && h.DstPort >= m.Min && h.DstPort <= m.Max
+}
+
+func (m *L4MatchDstPort) MarshalJSON() ([]byte, error) {
+	return marshalPortRange(m.Min, m.Max)
+}
+
+func (m *L4MatchDstPort) UnmarshalJSON(b []byte) error {
+	var err error
+	m.Min, m.Max, err = unmarshalPortRange(b, TypeL4MatchDstPort)
+	return err
+}
+
+func marshalPortRange(min, max uint16) ([]byte, error) {
+	return json.Marshal(
+		jsonContainer{
+			"Min": strconv.Itoa(int(min)),
+			"Max": strconv.Itoa(int(max)),
+		},
+	)
+}
+
+func unmarshalPortRange(b []byte, name string) (uint16, uint16, error) {
+	min, err := unmarshalUintField(b, name, "Min", 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	max, err := unmarshalUintField(b, name, "Max", 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	if min > max {
+		return 0, 0, common.NewBasicError("Invalid port range", nil,
+			"name", name, "min", min, "max", max)
+	}
+	return uint16(min), uint16(max), nil
+}
@@ -0,0 +1,377 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// This file contains a parser and printer for a compact text syntax of
+// conditions. The grammar is:
+//
+//   cond  = "all" "(" [list] ")" | "any" "(" [list] ")" | "not" "(" cond ")" |
+//           "true" | "false" | key "=" value
+//   list  = cond { "," cond }
+//   key   = "src" | "dst" | "tos" | "dscp" | "tc" | "flowlabel" | "proto" |
+//           "srcport" | "dstport"
+//
+// Networks for src and dst are written in CIDR notation; depending on the
+// address family they match the IPv4 or IPv6 header. tos and dscp match IPv4
+// packets, tc (traffic class) and flowlabel match IPv6 packets. Numbers can be
+// written in decimal or in hex with a 0x prefix. proto also accepts the names
+// tcp, udp, icmp and icmpv6. Port values are either a single port or an
+// inclusive range, e.g., 1024-65535. Whitespace between tokens is ignored.
+//
+// Example:
+//   all(src=10.0.0.0/8, not(dscp=0x2e), any(dstport=53, dstport=8000-8080))
+
+package pktcls
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/google/gopacket/layers"
+
+	"github.com/scionproto/scion/go/lib/common"
+)
+
+// ParseCond parses a condition in text syntax. Errors contain the byte offset
+// of the offending token in s.
+func ParseCond(s string) (Cond, error) {
+	p := &condParser{lexer: condLexer{input: s}}
+	p.next()
+	cond, err := p.parseCond()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("Unexpected trailing input")
+	}
+	return cond, nil
+}
+
+// FormatCond returns the text syntax of c, such that ParseCond returns an
+// identical condition. An error is returned if c contains conditions that
+// cannot be expressed in text syntax, e.g., path predicates.
+func FormatCond(c Cond) (string, error) {
+	switch c := c.(type) {
+	case CondAllOf:
+		return formatCondSlice("all", c)
+	case CondAnyOf:
+		return formatCondSlice("any", c)
+	case CondNot:
+		s, err := FormatCond(c.Operand)
+		if err != nil {
+			return "", err
+		}
+		return "not(" + s + ")", nil
+	case CondBool:
+		return strconv.FormatBool(bool(c)), nil
+	case *CondIPv4:
+		switch p := c.Predicate.(type) {
+		case *IPv4MatchSource:
+			return "src=" + p.Net.String(), nil
+		case *IPv4MatchDestination:
+			return "dst=" + p.Net.String(), nil
+		case *IPv4MatchToS:
+			return fmt.Sprintf("tos=%#x", p.TOS), nil
+		case *IPv4MatchDSCP:
+			return fmt.Sprintf("dscp=%#x", p.DSCP), nil
+		}
+	case *CondIPv6:
+		switch p := c.Predicate.(type) {
+		case *IPv6MatchSource:
+			return "src=" + p.Net.String(), nil
+		case *IPv6MatchDestination:
+			return "dst=" + p.Net.String(), nil
+		case *IPv6MatchTrafficClass:
+			return fmt.Sprintf("tc=%#x", p.TrafficClass), nil
+		case *IPv6MatchFlowLabel:
+			return fmt.Sprintf("flowlabel=%#x", p.FlowLabel), nil
+		}
+	case *CondL4:
+		switch p := c.Predicate.(type) {
+		case *L4MatchProtocol:
+			return fmt.Sprintf("proto=%d", p.Protocol), nil
+		case *L4MatchSrcPort:
+			return "srcport=" + formatPortRange(p.Min, p.Max), nil
+		case *L4MatchDstPort:
+			return "dstport=" + formatPortRange(p.Min, p.Max), nil
+		}
+	}
+	return "", common.NewBasicError("Condition not supported in text syntax", nil,
+		"type", common.TypeOf(c))
+}
+
+func formatCondSlice(op string, conds []Cond) (string, error) {
+	var children []string
+	for _, cond := range conds {
+		s, err := FormatCond(cond)
+		if err != nil {
+			return "", err
+		}
+		children = append(children, s)
+	}
+	return op + "(" + strings.Join(children, ", ") + ")", nil
+}
+
+func formatPortRange(min, max uint16) string {
+	if min == max {
+		return strconv.Itoa(int(min))
+	}
+	return fmt.Sprintf("%d-%d", min, max)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokLParen
+	tokRParen
+	tokComma
+	tokEqual
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of input"
+	}
+	return t.val
+}
+
+var punctuation = map[byte]tokenKind{
+	'(': tokLParen,
+	')': tokRParen,
+	',': tokComma,
+	'=': tokEqual,
+}
+
+// condLexer splits the input into words and the punctuation characters
+// "(", ")", "," and "=".
+type condLexer struct {
+	input string
+	pos   int
+}
+
+func (l *condLexer) next() token {
+	for l.pos < len(l.input) && isSpace(l.input[l.pos]) {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos == len(l.input) {
+		return token{kind: tokEOF, pos: start}
+	}
+	if kind, ok := punctuation[l.input[l.pos]]; ok {
+		l.pos++
+		return token{kind: kind, val: l.input[start:l.pos], pos: start}
+	}
+	for l.pos < len(l.input) && !isSpace(l.input[l.pos]) {
+		if _, ok := punctuation[l.input[l.pos]]; ok {
+			break
+		}
+		l.pos++
+	}
+	return token{kind: tokWord, val: l.input[start:l.pos], pos: start}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+type condParser struct {
+	lexer condLexer
+	// tok is the current token.
+	tok token
+}
+
+func (p *condParser) next() {
+	p.tok = p.lexer.next()
+}
+
+func (p *condParser) errorf(msg string) error {
+	return common.NewBasicError(msg, nil, "pos", p.tok.pos, "token", p.tok)
+}
+
+func (p *condParser) expect(kind tokenKind, what string) error {
+	if p.tok.kind != kind {
+		return p.errorf("Expected " + what)
+	}
+	p.next()
+	return nil
+}
+
+func (p *condParser) parseCond() (Cond, error) {
+	if p.tok.kind != tokWord {
+		return nil, p.errorf("Expected condition")
+	}
+	word := p.tok
+	p.next()
+	switch word.val {
+	case "all":
+		conds, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return NewCondAllOf(conds...), nil
+	case "any":
+		conds, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return NewCondAnyOf(conds...), nil
+	case "not":
+		if err := p.expect(tokLParen, `"("`); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseCond()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokRParen, `")"`); err != nil {
+			return nil, err
+		}
+		return NewCondNot(operand), nil
+	case "true":
+		return CondTrue, nil
+	case "false":
+		return CondFalse, nil
+	}
+	if _, ok := predicateKeys[word.val]; !ok {
+		return nil, common.NewBasicError("Unknown condition", nil, "pos", word.pos,
+			"token", word)
+	}
+	if err := p.expect(tokEqual, `"=" after predicate key`); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokWord {
+		return nil, p.errorf("Expected predicate value")
+	}
+	value := p.tok
+	p.next()
+	cond, err := newPredicateCond(word.val, value.val)
+	if err != nil {
+		return nil, common.NewBasicError("Invalid predicate value", err, "pos", value.pos,
+			"token", value)
+	}
+	return cond, nil
+}
+
+// parseList parses a parenthesized, possibly empty, list of conditions.
+func (p *condParser) parseList() ([]Cond, error) {
+	if err := p.expect(tokLParen, `"("`); err != nil {
+		return nil, err
+	}
+	var conds []Cond
+	if p.tok.kind == tokRParen {
+		p.next()
+		return conds, nil
+	}
+	for {
+		cond, err := p.parseCond()
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+		switch p.tok.kind {
+		case tokComma:
+			p.next()
+		case tokRParen:
+			p.next()
+			return conds, nil
+		default:
+			return nil, p.errorf(`Expected "," or ")"`)
+		}
+	}
+}
+
+var predicateKeys = map[string]struct{}{
+	"src": {}, "dst": {}, "tos": {}, "dscp": {}, "tc": {}, "flowlabel": {},
+	"proto": {}, "srcport": {}, "dstport": {},
+}
+
+var protocolNames = map[string]layers.IPProtocol{
+	"tcp":    layers.IPProtocolTCP,
+	"udp":    layers.IPProtocolUDP,
+	"icmp":   layers.IPProtocolICMPv4,
+	"icmpv6": layers.IPProtocolICMPv6,
+}
+
+func newPredicateCond(key, value string) (Cond, error) {
+	switch key {
+	case "src", "dst":
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		if network.IP.To4() != nil {
+			if key == "src" {
+				return NewCondIPv4(&IPv4MatchSource{Net: network}), nil
+			}
+			return NewCondIPv4(&IPv4MatchDestination{Net: network}), nil
+		}
+		if key == "src" {
+			return NewCondIPv6(&IPv6MatchSource{Net: network}), nil
+		}
+		return NewCondIPv6(&IPv6MatchDestination{Net: network}), nil
+	case "tos":
+		i, err := strconv.ParseUint(value, 0, 8)
+		return NewCondIPv4(&IPv4MatchToS{TOS: uint8(i)}), err
+	case "dscp":
+		i, err := strconv.ParseUint(value, 0, 6)
+		return NewCondIPv4(&IPv4MatchDSCP{DSCP: uint8(i)}), err
+	case "tc":
+		i, err := strconv.ParseUint(value, 0, 8)
+		return NewCondIPv6(&IPv6MatchTrafficClass{TrafficClass: uint8(i)}), err
+	case "flowlabel":
+		i, err := strconv.ParseUint(value, 0, 20)
+		return NewCondIPv6(&IPv6MatchFlowLabel{FlowLabel: uint32(i)}), err
+	case "proto":
+		if proto, ok := protocolNames[value]; ok {
+			return NewCondL4(&L4MatchProtocol{Protocol: uint8(proto)}), nil
+		}
+		i, err := strconv.ParseUint(value, 0, 8)
+		return NewCondL4(&L4MatchProtocol{Protocol: uint8(i)}), err
+	case "srcport":
+		min, max, err := parsePortRange(value)
+		return NewCondL4(&L4MatchSrcPort{Min: min, Max: max}), err
+	case "dstport":
+		min, max, err := parsePortRange(value)
+		return NewCondL4(&L4MatchDstPort{Min: min, Max: max}), err
+	}
+	return nil, common.NewBasicError("Unknown predicate key", nil, "key", key)
+}
+
+func parsePortRange(s string) (uint16, uint16, error) {
+	parts := strings.SplitN(s, "-", 2)
+	min, err := strconv.ParseUint(parts[0], 0, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(parts) == 1 {
+		return uint16(min), uint16(min), nil
+	}
+	max, err := strconv.ParseUint(parts[1], 0, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	if min > max {
+		return 0, 0, common.NewBasicError("Invalid port range", nil, "min", min, "max", max)
+	}
+	return uint16(min), uint16(max), nil
+}
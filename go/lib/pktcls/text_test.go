@@ -0,0 +1,129 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pktcls
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+)
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+func TestParseCond(t *testing.T) {
+	testCases := []struct {
+		Text     string
+		Cond     Cond
+		Expected string
+	}{
+		{
+			Text: "all(src=10.0.0.0/8, not(dscp=0x2e))",
+			Cond: NewCondAllOf(
+				NewCondIPv4(&IPv4MatchSource{Net: mustParseCIDR("10.0.0.0/8")}),
+				NewCondNot(NewCondIPv4(&IPv4MatchDSCP{DSCP: 0x2e})),
+			),
+			Expected: "all(src=10.0.0.0/8, not(dscp=0x2e))",
+		},
+		{
+			Text: " any ( dst=2001:db8::/32,tc=32 , flowlabel=0xbeef,true ) ",
+			Cond: NewCondAnyOf(
+				NewCondIPv6(&IPv6MatchDestination{Net: mustParseCIDR("2001:db8::/32")}),
+				NewCondIPv6(&IPv6MatchTrafficClass{TrafficClass: 32}),
+				NewCondIPv6(&IPv6MatchFlowLabel{FlowLabel: 0xbeef}),
+				CondTrue,
+			),
+			Expected: "any(dst=2001:db8::/32, tc=0x20, flowlabel=0xbeef, true)",
+		},
+		{
+			Text: "all(proto=udp, dstport=53, srcport=1024-65535, tos=0, false)",
+			Cond: NewCondAllOf(
+				NewCondL4(&L4MatchProtocol{Protocol: 17}),
+				NewCondL4(&L4MatchDstPort{Min: 53, Max: 53}),
+				NewCondL4(&L4MatchSrcPort{Min: 1024, Max: 65535}),
+				NewCondIPv4(&IPv4MatchToS{TOS: 0}),
+				CondFalse,
+			),
+			Expected: "all(proto=17, dstport=53, srcport=1024-65535, tos=0x0, false)",
+		},
+		{
+			Text:     "all()",
+			Cond:     NewCondAllOf(),
+			Expected: "all()",
+		},
+	}
+	Convey("ParseCond should build the condition and FormatCond print it", t, func() {
+		for _, tc := range testCases {
+			Convey(tc.Text, func() {
+				cond, err := ParseCond(tc.Text)
+				SoMsg("err", err, ShouldBeNil)
+				SoMsg("cond", cond, ShouldResemble, tc.Cond)
+				s, err := FormatCond(cond)
+				SoMsg("format err", err, ShouldBeNil)
+				SoMsg("text", s, ShouldEqual, tc.Expected)
+				cond, err = ParseCond(s)
+				SoMsg("reparse err", err, ShouldBeNil)
+				SoMsg("reparse", cond, ShouldResemble, tc.Cond)
+			})
+		}
+	})
+}
+
+func TestParseCondErrors(t *testing.T) {
+	testCases := []struct {
+		Text string
+		Pos  int
+	}{
+		{"", 0},
+		{"all(src=10.0.0.0/8", 18},
+		{"all(src=10.0.0.0/8,)", 19},
+		{"all(src=10.0.0.0/8 dst=10.0.0.0/8)", 19},
+		{"not(true, false)", 8},
+		{"all(foo=1)", 4},
+		{"all(src=10.0.0.0/33)", 8},
+		{"all(dscp=0x40)", 9},
+		{"any(dstport=2000-1000)", 12},
+		{"any(proto=)", 10},
+		{"true)", 4},
+	}
+	Convey("ParseCond should report the position of the error", t, func() {
+		for _, tc := range testCases {
+			Convey(tc.Text, func() {
+				_, err := ParseCond(tc.Text)
+				SoMsg("err", err, ShouldNotBeNil)
+				SoMsg("pos", err.Error(), ShouldContainSubstring,
+					fmt.Sprintf("pos=\"%d\"", tc.Pos))
+			})
+		}
+	})
+}
+
+func TestFormatCondUnsupported(t *testing.T) {
+	Convey("FormatCond should fail on path predicates", t, func() {
+		pp, err := spathmeta.NewPathPredicate("1-0#0")
+		SoMsg("pp err", err, ShouldBeNil)
+		_, err = FormatCond(NewCondAllOf(NewCondPathPredicate(pp)))
+		SoMsg("err", err, ShouldNotBeNil)
+	})
+}
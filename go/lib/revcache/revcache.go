@@ -15,6 +15,7 @@
 package revcache
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -37,10 +38,29 @@ func NewKey(ia addr.IA, ifid common.IFIDType) *Key {
 	}
 }
 
+// IA returns the AS of the revoked interface.
+func (k Key) IA() addr.IA {
+	return k.ia
+}
+
+// IfID returns the ID of the revoked interface.
+func (k Key) IfID() common.IFIDType {
+	return k.ifid
+}
+
 func (k Key) String() string {
 	return fmt.Sprintf("%s#%s", k.ia, k.ifid)
 }
 
+// Entry is a revocation stored in the cache.
+type Entry struct {
+	Key Key
+	Rev *path_mgmt.SignedRevInfo
+	// Expiry is the time at which the revocation is no longer returned by
+	// the cache.
+	Expiry time.Time
+}
+
 // RevCache is a cache for revocations.
 type RevCache interface {
 	// Get item with key k from the cache. Returns the item or nil,
@@ -52,4 +72,13 @@ type RevCache interface {
 	// if now + ttl is at a later point in time than the current expiry.
 	// Returns whether an update was performed or not.
 	Set(k *Key, rev *path_mgmt.SignedRevInfo, ttl time.Duration) bool
+	// GetAll returns all active revocations of interfaces in AS ia, ordered
+	// by interface ID.
+	GetAll(ctx context.Context, ia addr.IA) ([]*Entry, error)
+	// GetExpiring returns all active revocations that expire before
+	// deadline, ordered by expiry.
+	GetExpiring(ctx context.Context, deadline time.Time) ([]*Entry, error)
+	// ForEach calls f for every active revocation, in no particular order,
+	// until f returns false. f must not call methods of the cache.
+	ForEach(ctx context.Context, f func(*Entry) bool) error
 }
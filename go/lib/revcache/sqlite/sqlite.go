@@ -0,0 +1,227 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sqlite contains a persistent revocation cache backed by an SQLite
+// database.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/revcache"
+	"github.com/scionproto/scion/go/lib/sqlite"
+	"github.com/scionproto/scion/go/proto"
+)
+
+var _ revcache.RevCache = (*Backend)(nil)
+
+// Backend is a revocation cache that stores revocations in an SQLite
+// database, such that they survive restarts. Expired revocations are never
+// returned, but they are only removed from the database by DeleteExpired.
+type Backend struct {
+	sync.RWMutex
+	db *sql.DB
+}
+
+// New returns a new SQLite backend opening a database at the given path. If
+// no database exists a new database is be created. If the schema version of the
+// stored database is different from the one in schema.go, an error is returned.
+func New(path string) (*Backend, error) {
+	db, err := sqlite.New(path, Schema, SchemaVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{
+		db: db,
+	}, nil
+}
+
+// Get returns the revocation for key k, if it exists and has not expired.
+// Database errors are logged and reported as a cache miss.
+func (b *Backend) Get(k *revcache.Key) (*path_mgmt.SignedRevInfo, bool) {
+	b.RLock()
+	defer b.RUnlock()
+	ia := k.IA()
+	var rawRev common.RawBytes
+	err := b.db.QueryRow(
+		"SELECT RawRev FROM Revocations WHERE IsdID=? AND AsID=? AND IfID=? AND Expiry>?",
+		ia.I, ia.A, k.IfID(), time.Now().UnixNano()).Scan(&rawRev)
+	if err == sql.ErrNoRows {
+		return nil, false
+	}
+	if err != nil {
+		log.Error("Failed to look up revocation", "key", k, "err", err)
+		return nil, false
+	}
+	rev, err := path_mgmt.NewSignedRevInfoFromRaw(rawRev)
+	if err != nil {
+		log.Error("Failed to parse stored revocation", "key", k, "err", err)
+		return nil, false
+	}
+	return rev, true
+}
+
+// Set stores rev under key k for the duration ttl. An existing, unexpired
+// revocation is only replaced if the new one expires later. Database errors
+// are logged and reported as no update.
+func (b *Backend) Set(k *revcache.Key, rev *path_mgmt.SignedRevInfo, ttl time.Duration) bool {
+	b.Lock()
+	defer b.Unlock()
+	updated, err := b.set(k, rev, ttl)
+	if err != nil {
+		log.Error("Failed to store revocation", "key", k, "err", err)
+		return false
+	}
+	return updated
+}
+
+func (b *Backend) set(k *revcache.Key, rev *path_mgmt.SignedRevInfo,
+	ttl time.Duration) (bool, error) {
+
+	rawRev, err := proto.PackRoot(rev)
+	if err != nil {
+		return false, common.NewBasicError("Failed to pack revocation", err)
+	}
+	ia := k.IA()
+	expiry := time.Now().Add(ttl).UnixNano()
+	tx, err := b.db.Begin()
+	if err != nil {
+		return false, common.NewBasicError("Failed to create transaction", err)
+	}
+	var curExpiry int64
+	err = tx.QueryRow("SELECT Expiry FROM Revocations WHERE IsdID=? AND AsID=? AND IfID=?",
+		ia.I, ia.A, k.IfID()).Scan(&curExpiry)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		tx.Rollback()
+		return false, common.NewBasicError("Failed to look up revocation", err)
+	case curExpiry > time.Now().UnixNano() && expiry <= curExpiry:
+		// The stored revocation is still valid for longer.
+		tx.Rollback()
+		return false, nil
+	}
+	_, err = tx.Exec(`INSERT OR REPLACE INTO Revocations (IsdID, AsID, IfID, Expiry, RawRev)
+		VALUES (?, ?, ?, ?, ?)`, ia.I, ia.A, k.IfID(), expiry, rawRev)
+	if err != nil {
+		tx.Rollback()
+		return false, common.NewBasicError("Failed to insert revocation", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, common.NewBasicError("Failed to commit transaction", err)
+	}
+	return true, nil
+}
+
+func (b *Backend) GetAll(ctx context.Context, ia addr.IA) ([]*revcache.Entry, error) {
+	return b.getEntries(ctx, "IsdID=? AND AsID=?", "IfID", ia.I, ia.A)
+}
+
+func (b *Backend) GetExpiring(ctx context.Context,
+	deadline time.Time) ([]*revcache.Entry, error) {
+
+	return b.getEntries(ctx, "Expiry<?", "Expiry", deadline.UnixNano())
+}
+
+func (b *Backend) getEntries(ctx context.Context, where, order string,
+	args ...interface{}) ([]*revcache.Entry, error) {
+
+	var entries []*revcache.Entry
+	err := b.forEach(ctx, where, order, args, func(e *revcache.Entry) bool {
+		entries = append(entries, e)
+		return true
+	})
+	return entries, err
+}
+
+func (b *Backend) ForEach(ctx context.Context, f func(*revcache.Entry) bool) error {
+	return b.forEach(ctx, "", "", nil, f)
+}
+
+// forEach calls f for every active revocation matching the where clause,
+// ordered by the given column, until f returns false.
+func (b *Backend) forEach(ctx context.Context, where, order string, args []interface{},
+	f func(*revcache.Entry) bool) error {
+
+	b.RLock()
+	defer b.RUnlock()
+	stmt := "SELECT IsdID, AsID, IfID, Expiry, RawRev FROM Revocations WHERE Expiry>?"
+	if where != "" {
+		stmt += " AND " + where
+	}
+	if order != "" {
+		stmt += " ORDER BY " + order
+	}
+	args = append([]interface{}{time.Now().UnixNano()}, args...)
+	rows, err := b.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return common.NewBasicError("Failed to look up revocations", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var ia addr.IA
+		var ifID common.IFIDType
+		var expiry int64
+		var rawRev common.RawBytes
+		if err := rows.Scan(&ia.I, &ia.A, &ifID, &expiry, &rawRev); err != nil {
+			return common.NewBasicError("Error reading DB response", err)
+		}
+		rev, err := path_mgmt.NewSignedRevInfoFromRaw(rawRev)
+		if err != nil {
+			return common.NewBasicError("Failed to parse stored revocation", err)
+		}
+		e := &revcache.Entry{
+			Key:    *revcache.NewKey(ia, ifID),
+			Rev:    rev,
+			Expiry: time.Unix(0, expiry),
+		}
+		if !f(e) {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return common.NewBasicError("Error reading DB response", err)
+	}
+	return nil
+}
+
+// DeleteExpired deletes all revocations that expired before now. Returns the
+// number of deleted revocations.
+func (b *Backend) DeleteExpired(ctx context.Context, now time.Time) (int, error) {
+	b.Lock()
+	defer b.Unlock()
+	res, err := b.db.ExecContext(ctx, "DELETE FROM Revocations WHERE Expiry<?",
+		now.UnixNano())
+	if err != nil {
+		return 0, common.NewBasicError("Failed to delete revocations", err)
+	}
+	deleted, _ := res.RowsAffected()
+	return int(deleted), nil
+}
+
+// Close closes the underlying database.
+func (b *Backend) Close() error {
+	b.Lock()
+	defer b.Unlock()
+	return b.db.Close()
+}
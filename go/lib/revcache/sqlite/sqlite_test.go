@@ -0,0 +1,187 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
+	"github.com/scionproto/scion/go/lib/revcache"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/proto"
+)
+
+var (
+	ia110 = addr.IA{I: 1, A: 0xff0000000110}
+	ia120 = addr.IA{I: 1, A: 0xff0000000120}
+
+	timeout = time.Second
+)
+
+func setupDB(t *testing.T) (*Backend, string) {
+	tmpFile := xtest.MustTempFileName("", "revcache-sqlite-")
+	b, err := New(tmpFile)
+	xtest.FailOnErr(t, err)
+	return b, tmpFile
+}
+
+// allocRev returns a signed revocation whose blob identifies the interface.
+// The blob is not a valid RevInfo, as the cache does not parse it.
+func allocRev(ia addr.IA, ifID common.IFIDType) *path_mgmt.SignedRevInfo {
+	return &path_mgmt.SignedRevInfo{
+		Blob: common.RawBytes(fmt.Sprintf("%s#%d", ia, ifID)),
+		Sign: proto.NewSignS(proto.SignType_none, nil),
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	Convey("Set should store revocations that Get returns until they expire", t, func() {
+		b, tmpF := setupDB(t)
+		defer os.Remove(tmpF)
+		defer b.Close()
+		k := revcache.NewKey(ia110, 1)
+		rev := allocRev(ia110, 1)
+		_, ok := b.Get(k)
+		SoMsg("Not found before Set", ok, ShouldBeFalse)
+		SoMsg("Set", b.Set(k, rev, time.Hour), ShouldBeTrue)
+		res, ok := b.Get(k)
+		SoMsg("Found", ok, ShouldBeTrue)
+		SoMsg("Blob", res.Blob, ShouldResemble, rev.Blob)
+		_, ok = b.Get(revcache.NewKey(ia110, 2))
+		SoMsg("Other key not found", ok, ShouldBeFalse)
+		// Shorter TTLs do not replace the stored revocation.
+		SoMsg("Set shorter", b.Set(k, rev, time.Minute), ShouldBeFalse)
+		SoMsg("Set longer", b.Set(k, rev, 2*time.Hour), ShouldBeTrue)
+		// Expired revocations are not returned, and can be replaced.
+		k2 := revcache.NewKey(ia110, 2)
+		SoMsg("Set expiring", b.Set(k2, rev, time.Nanosecond), ShouldBeTrue)
+		time.Sleep(time.Millisecond)
+		_, ok = b.Get(k2)
+		SoMsg("Expired not found", ok, ShouldBeFalse)
+		SoMsg("Set after expiry", b.Set(k2, rev, time.Nanosecond), ShouldBeTrue)
+	})
+	Convey("Revocations should survive a restart", t, func() {
+		b, tmpF := setupDB(t)
+		defer os.Remove(tmpF)
+		k := revcache.NewKey(ia110, 1)
+		b.Set(k, allocRev(ia110, 1), time.Hour)
+		b.Close()
+		b, err := New(tmpF)
+		xtest.FailOnErr(t, err)
+		defer b.Close()
+		_, ok := b.Get(k)
+		SoMsg("Found", ok, ShouldBeTrue)
+	})
+}
+
+func TestGetAll(t *testing.T) {
+	Convey("GetAll should return all unexpired revocations of an AS", t, func() {
+		b, tmpF := setupDB(t)
+		defer os.Remove(tmpF)
+		defer b.Close()
+		ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+		defer cancelF()
+		rev1 := allocRev(ia110, 1)
+		rev2 := allocRev(ia110, 2)
+		b.Set(revcache.NewKey(ia110, 2), rev2, time.Hour)
+		b.Set(revcache.NewKey(ia110, 1), rev1, time.Hour)
+		b.Set(revcache.NewKey(ia110, 3), allocRev(ia110, 3), time.Nanosecond)
+		b.Set(revcache.NewKey(ia120, 1), allocRev(ia120, 1), time.Hour)
+		time.Sleep(time.Millisecond)
+		entries, err := b.GetAll(ctx, ia110)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("Count", len(entries), ShouldEqual, 2)
+		SoMsg("First key", entries[0].Key, ShouldResemble, *revcache.NewKey(ia110, 1))
+		SoMsg("First", entries[0].Rev.Blob, ShouldResemble, rev1.Blob)
+		SoMsg("Second key", entries[1].Key, ShouldResemble, *revcache.NewKey(ia110, 2))
+		SoMsg("Second", entries[1].Rev.Blob, ShouldResemble, rev2.Blob)
+	})
+}
+
+func TestGetExpiring(t *testing.T) {
+	Convey("GetExpiring should return active revocations expiring before the deadline", t,
+		func() {
+			b, tmpF := setupDB(t)
+			defer os.Remove(tmpF)
+			defer b.Close()
+			ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+			defer cancelF()
+			b.Set(revcache.NewKey(ia110, 1), allocRev(ia110, 1), 2*time.Minute)
+			b.Set(revcache.NewKey(ia120, 1), allocRev(ia120, 1), time.Minute)
+			b.Set(revcache.NewKey(ia110, 2), allocRev(ia110, 2), time.Hour)
+			b.Set(revcache.NewKey(ia110, 3), allocRev(ia110, 3), time.Nanosecond)
+			time.Sleep(time.Millisecond)
+			entries, err := b.GetExpiring(ctx, time.Now().Add(10*time.Minute))
+			SoMsg("err", err, ShouldBeNil)
+			SoMsg("Count", len(entries), ShouldEqual, 2)
+			SoMsg("First", entries[0].Key, ShouldResemble, *revcache.NewKey(ia120, 1))
+			SoMsg("Second", entries[1].Key, ShouldResemble, *revcache.NewKey(ia110, 1))
+			SoMsg("Expiry order", entries[0].Expiry, ShouldHappenBefore, entries[1].Expiry)
+		})
+}
+
+func TestForEach(t *testing.T) {
+	Convey("ForEach should visit active revocations until told to stop", t, func() {
+		b, tmpF := setupDB(t)
+		defer os.Remove(tmpF)
+		defer b.Close()
+		ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+		defer cancelF()
+		b.Set(revcache.NewKey(ia110, 1), allocRev(ia110, 1), time.Hour)
+		b.Set(revcache.NewKey(ia120, 1), allocRev(ia120, 1), time.Hour)
+		b.Set(revcache.NewKey(ia110, 2), allocRev(ia110, 2), time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		var visited int
+		err := b.ForEach(ctx, func(*revcache.Entry) bool {
+			visited++
+			return true
+		})
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("Visited all", visited, ShouldEqual, 2)
+		visited = 0
+		err = b.ForEach(ctx, func(*revcache.Entry) bool {
+			visited++
+			return false
+		})
+		SoMsg("err stop", err, ShouldBeNil)
+		SoMsg("Visited one", visited, ShouldEqual, 1)
+	})
+}
+
+func TestDeleteExpired(t *testing.T) {
+	Convey("DeleteExpired should only remove expired revocations", t, func() {
+		b, tmpF := setupDB(t)
+		defer os.Remove(tmpF)
+		defer b.Close()
+		ctx, cancelF := context.WithTimeout(context.Background(), timeout)
+		defer cancelF()
+		b.Set(revcache.NewKey(ia110, 1), allocRev(ia110, 1), time.Hour)
+		b.Set(revcache.NewKey(ia110, 2), allocRev(ia110, 2), time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		deleted, err := b.DeleteExpired(ctx, time.Now())
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("Deleted", deleted, ShouldEqual, 1)
+		_, ok := b.Get(revcache.NewKey(ia110, 1))
+		SoMsg("Unexpired kept", ok, ShouldBeTrue)
+	})
+}
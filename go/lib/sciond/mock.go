@@ -151,6 +151,11 @@ func (m *MockConn) RevNotification(sRevInfo *path_mgmt.SignedRevInfo) (*RevReply
 	}, nil
 }
 
+// RevList is not implemented.
+func (m *MockConn) RevList(ia addr.IA, expiringWithin time.Duration) (*RevListReply, error) {
+	panic("not implemented")
+}
+
 // Close is a no-op.
 func (m *MockConn) Close() error {
 	return nil
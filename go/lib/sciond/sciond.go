@@ -114,6 +114,12 @@ type Connector interface {
 	RevNotificationFromRaw(b []byte) (*RevReply, error)
 	// RevNotification sends a RevocationInfo message to SCIOND.
 	RevNotification(sRevInfo *path_mgmt.SignedRevInfo) (*RevReply, error)
+	// RevList requests from SCIOND the revocations it currently holds. If ia
+	// is not zero, only revocations of interfaces in AS ia are returned. If
+	// expiringWithin is not zero, only revocations that expire within
+	// expiringWithin are returned. If SCIOND fails to list the revocations,
+	// the error code of the reply is set.
+	RevList(ia addr.IA, expiringWithin time.Duration) (*RevListReply, error)
 	// Close shuts down the connection to a SCIOND server.
 	Close() error
 }
@@ -336,6 +342,28 @@ func (c *connector) RevNotification(sRevInfo *path_mgmt.SignedRevInfo) (*RevRepl
 	return &reply.(*Pld).RevReply, nil
 }
 
+func (c *connector) RevList(ia addr.IA, expiringWithin time.Duration) (*RevListReply, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	reply, err := c.dispatcher.Request(
+		context.Background(),
+		&Pld{
+			Id:    c.nextID(),
+			Which: proto.SCIONDMsg_Which_revListReq,
+			RevListReq: RevListReq{
+				Isdas:          ia.IAInt(),
+				ExpiringWithin: uint32(expiringWithin / time.Second),
+			},
+		},
+		reliable.NilAppAddr,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &reply.(*Pld).RevListReply, nil
+}
+
 func (c *connector) Close() error {
 	return c.dispatcher.Close(context.Background())
 }
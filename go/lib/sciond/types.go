@@ -67,6 +67,8 @@ type Pld struct {
 	IfInfoReply        IFInfoReply
 	ServiceInfoRequest ServiceInfoRequest
 	ServiceInfoReply   ServiceInfoReply
+	RevListReq         RevListReq
+	RevListReply       RevListReply
 }
 
 func NewPldFromRaw(b common.RawBytes) (*Pld, error) {
@@ -111,6 +113,10 @@ func (p *Pld) union() (interface{}, error) {
 		return p.ServiceInfoRequest, nil
 	case proto.SCIONDMsg_Which_serviceInfoReply:
 		return p.ServiceInfoReply, nil
+	case proto.SCIONDMsg_Which_revListReq:
+		return p.RevListReq, nil
+	case proto.SCIONDMsg_Which_revListReply:
+		return p.RevListReply, nil
 	}
 	return nil, common.NewBasicError("Unsupported SCIOND union type", nil, "type", p.Which)
 }
@@ -132,7 +138,46 @@ func (pathReq *PathReq) Copy() *PathReq {
 }
 
 type PathReqFlags struct {
-	Refresh bool
+	Refresh  bool
+	Ordering PathOrdering
+	// Disjoint requests up to MaxPaths paths (all if 0) that share as few
+	// interfaces as possible, preferring paths earlier in the ordering.
+	Disjoint bool
+}
+
+// PathOrdering selects how SCIOND orders the paths in a reply.
+type PathOrdering uint16
+
+const (
+	// OrderShortest orders paths by the number of AS hops.
+	OrderShortest PathOrdering = iota
+	// OrderDisjoint orders paths such that each path shares as few interfaces
+	// as possible with the preceding paths.
+	OrderDisjoint
+	// OrderFewestISDs orders paths by the number of ISDs they traverse.
+	OrderFewestISDs
+	// OrderPeering orders paths crossing a peering link first.
+	OrderPeering
+	// OrderShortcuts orders paths that avoid the core by taking a shortcut
+	// first.
+	OrderShortcuts
+)
+
+func (o PathOrdering) String() string {
+	switch o {
+	case OrderShortest:
+		return "shortest"
+	case OrderDisjoint:
+		return "disjoint"
+	case OrderFewestISDs:
+		return "fewestISDs"
+	case OrderPeering:
+		return "peering"
+	case OrderShortcuts:
+		return "shortcuts"
+	default:
+		return fmt.Sprintf("Unknown ordering (%v)", uint16(o))
+	}
 }
 
 type PathReply struct {
@@ -178,6 +223,7 @@ type FwdPathMeta struct {
 	Mtu        uint16
 	Interfaces []PathInterface
 	ExpTime    uint32
+	Metadata   PathMetadata
 }
 
 func (fpm *FwdPathMeta) SrcIA() addr.IA {
@@ -222,6 +268,21 @@ func (fpm *FwdPathMeta) fmtIfaces() []string {
 	return hops
 }
 
+// PathMetadata contains properties of a path that applications can use to
+// rank paths.
+type PathMetadata struct {
+	// Hops is the number of inter-AS links on the path.
+	Hops uint8
+	// ISDs is the number of distinct ISDs on the path.
+	ISDs uint8 `capnp:"isds"`
+	// Peering is true if the path crosses at least one peering link.
+	Peering bool
+}
+
+func (m PathMetadata) String() string {
+	return fmt.Sprintf("Hops: %d ISDs: %d Peering: %t", m.Hops, m.ISDs, m.Peering)
+}
+
 type PathInterface struct {
 	RawIsdas addr.IAInt `capnp:"isdas"`
 	IfID     common.IFIDType
@@ -289,6 +350,55 @@ func (c RevResult) String() string {
 	}
 }
 
+type RevListReq struct {
+	Isdas addr.IAInt
+	// ExpiringWithin is in seconds.
+	ExpiringWithin uint32
+}
+
+type RevListReply struct {
+	Entries   []RevListReplyEntry
+	ErrorCode RevListErrorCode
+}
+
+type RevListErrorCode uint16
+
+const (
+	RevListOk RevListErrorCode = iota
+	RevListInternal
+)
+
+func (c RevListErrorCode) String() string {
+	switch c {
+	case RevListOk:
+		return "OK"
+	case RevListInternal:
+		return "SCIOND experienced an internal error"
+	default:
+		return fmt.Sprintf("Unknown error (%v)", uint16(c))
+	}
+}
+
+type RevListReplyEntry struct {
+	RawIsdas addr.IAInt `capnp:"isdas"`
+	IfID     common.IFIDType
+	ExpTime  uint32
+	SRevInfo *path_mgmt.SignedRevInfo
+}
+
+func (entry *RevListReplyEntry) ISD_AS() addr.IA {
+	return entry.RawIsdas.IA()
+}
+
+func (entry *RevListReplyEntry) Expiry() time.Time {
+	return util.USecsToTime(entry.ExpTime)
+}
+
+func (entry RevListReplyEntry) String() string {
+	return fmt.Sprintf("%s#%d expires: %s", entry.ISD_AS(), entry.IfID,
+		util.TimeToString(entry.Expiry()))
+}
+
 type IFInfoRequest struct {
 	IfIDs []common.IFIDType
 }
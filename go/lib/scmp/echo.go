@@ -0,0 +1,52 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scmp
+
+import (
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/l4"
+)
+
+// NewEchoRequest returns the SCMP header and raw payload of an echo request
+// carrying info. The timestamp of the header is set to the current time.
+func NewEchoRequest(info *InfoEcho) (*Hdr, common.RawBytes) {
+	meta := Meta{InfoLen: uint8(info.Len() / common.LineLen)}
+	pld := make(common.RawBytes, MetaLen+info.Len())
+	meta.Write(pld)
+	info.Write(pld[MetaLen:])
+	hdr := NewHdr(ClassType{Class: C_General, Type: T_G_EchoRequest}, len(pld))
+	return hdr, pld
+}
+
+// ParseEcho extracts the SCMP header and echo info from the layer 4 header
+// and payload of a parsed echo request or reply.
+func ParseEcho(l4h l4.L4Header, pld common.Payload) (*Hdr, *InfoEcho, error) {
+	hdr, ok := l4h.(*Hdr)
+	if !ok {
+		return nil, nil,
+			common.NewBasicError("Not an SCMP header", nil, "type", common.TypeOf(l4h))
+	}
+	scmpPld, ok := pld.(*Payload)
+	if !ok {
+		return nil, nil,
+			common.NewBasicError("Not an SCMP payload", nil, "type", common.TypeOf(pld))
+	}
+	info, ok := scmpPld.Info.(*InfoEcho)
+	if !ok {
+		return nil, nil,
+			common.NewBasicError("Not an Info Echo", nil, "type", common.TypeOf(scmpPld.Info))
+	}
+	return hdr, info, nil
+}
@@ -19,6 +19,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/hashicorp/golang-lru/simplelru"
+
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/assert"
 	"github.com/scionproto/scion/go/lib/common"
@@ -38,27 +40,11 @@ import (
 const (
 	// Receive and send buffer sizes
 	BufSize = 1<<16 - 1
+	// Maximum number of remote ASes for which an unconnected Conn remembers
+	// the last used path
+	PrefPathCacheSize = 1024
 )
 
-type Error interface {
-	error
-	SCMP() *scmp.Hdr
-}
-
-var _ Error = (*OpError)(nil)
-
-type OpError struct {
-	scmp *scmp.Hdr
-}
-
-func (e *OpError) SCMP() *scmp.Hdr {
-	return e.scmp
-}
-
-func (e *OpError) Error() string {
-	return e.scmp.String()
-}
-
 var _ net.Conn = (*Conn)(nil)
 var _ net.PacketConn = (*Conn)(nil)
 
@@ -84,6 +70,30 @@ type Conn struct {
 	scionNet *Network
 	// Key of last used path, used to select the same path for the next packet
 	prefPathKey spathmeta.PathKey
+	// For unconnected Conns, keys of the last used paths indexed by remote
+	// IA, used to select the same path for the next packet to the same AS
+	prefPaths *simplelru.LRU
+	// Path set by the application, used for all packets to remote ASes
+	path *sciond.PathReplyEntry
+	// Path selector set by the application
+	pathSelector PathSelector
+	scmpMutex    sync.Mutex
+	// Handler set by the application, called for each received SCMP message
+	scmpHandler SCMPHandler
+}
+
+// A PathSelector chooses the paths of the packets sent by a Conn.
+type PathSelector interface {
+	// SelectPath returns the path for the next packet to raddr. If it returns
+	// nil and no error, the Conn chooses the path itself.
+	SelectPath(raddr *Addr) (*sciond.PathReplyEntry, error)
+}
+
+// PathSelectorFunc is a function that implements PathSelector.
+type PathSelectorFunc func(raddr *Addr) (*sciond.PathReplyEntry, error)
+
+func (f PathSelectorFunc) SelectPath(raddr *Addr) (*sciond.PathReplyEntry, error) {
+	return f(raddr)
 }
 
 // DialSCION calls DialSCION on the default networking context.
@@ -103,6 +113,14 @@ func DialSCIONWithBindSVC(network string, laddr, raddr, baddr *Addr,
 	return DefNetwork.DialSCIONWithBindSVC(network, laddr, raddr, baddr, svc)
 }
 
+// DialSCIONWithPolicy calls DialSCIONWithPolicy on the default networking context.
+func DialSCIONWithPolicy(network string, laddr, raddr *Addr, policy string) (*Conn, error) {
+	if DefNetwork == nil {
+		return nil, common.NewBasicError("SCION network not initialized", nil)
+	}
+	return DefNetwork.DialSCIONWithPolicy(network, laddr, raddr, policy)
+}
+
 // ListenSCION calls ListenSCION on the default networking context.
 func ListenSCION(network string, laddr *Addr) (*Conn, error) {
 	if DefNetwork == nil {
@@ -120,8 +138,10 @@ func ListenSCIONWithBindSVC(network string, laddr, baddr *Addr, svc addr.HostSVC
 }
 
 // ReadFromSCION reads data into b, returning the length of copied data and the
-// address of the sender. If the remote address for the connection is already
-// known, ReadFromSCION returns an error.
+// address of the sender. The address contains the reversed path of the
+// received packet and the overlay next hop, so it can be passed to
+// WriteToSCION to reply without a path lookup. If the remote address for the
+// connection is already known, ReadFromSCION returns an error.
 func (c *Conn) ReadFromSCION(b []byte) (int, *Addr, error) {
 	return c.read(b, true)
 }
@@ -171,33 +191,16 @@ func (c *Conn) read(b []byte, from bool) (int, *Addr, error) {
 	}
 	// On UDP4 network we can get either UDP traffic or SCMP messages
 	if c.net == "udp4" {
-		// Extract remote address
-		remote = &Addr{
-			IA: pkt.SrcIA,
-			// Copy the address to prevent races. See
-			// https://github.com/scionproto/scion/issues/1659.
-			Host: pkt.SrcHost.Copy(),
-		}
-		// Extract path and last hop
-		if lastHop != nil {
-			path := pkt.Path
-			if err = path.Reverse(); err != nil {
-				return 0, nil,
-					common.NewBasicError("Unable to reverse path on received packet", err)
-			}
-			remote.Path = path
-			// Copy the address to prevent races. See
-			// https://github.com/scionproto/scion/issues/1659.
-			remote.NextHopHost = lastHop.Addr.Copy()
-			remote.NextHopPort = lastHop.Port
+		remote, err = newReplyAddr(pkt, lastHop)
+		if err != nil {
+			return 0, nil, err
 		}
 		switch hdr := pkt.L4.(type) {
 		case *l4.UDP:
 			remote.L4Port = hdr.SrcPort
 			return n, remote, nil
 		case *scmp.Hdr:
-			c.handleSCMP(hdr, pkt)
-			return n, remote, &OpError{scmp: hdr}
+			return n, remote, c.handleSCMP(hdr, pkt, remote)
 		default:
 			return n, remote, common.NewBasicError("Unexpected SCION L4 protocol", nil,
 				"expected", "UDP or SCMP", "actual", pkt.L4.L4Type())
@@ -206,48 +209,181 @@ func (c *Conn) read(b []byte, from bool) (int, *Addr, error) {
 	return 0, nil, common.NewBasicError("Unknown network", nil, "net", c.net)
 }
 
-func (c *Conn) handleSCMP(hdr *scmp.Hdr, pkt *spkt.ScnPkt) {
-	// Only handle revocations for now
-	if hdr.Class == scmp.C_Path && hdr.Type == scmp.T_P_RevokedIF {
-		c.handleSCMPRev(hdr, pkt)
-	} else {
-		log.Warn("Received unsupported SCMP message", "class", hdr.Class, "type", hdr.Type)
+// newReplyAddr returns the address of the sender of pkt. If lastHop is not
+// nil, the address also contains the reversed path of pkt and lastHop as the
+// overlay next hop, such that replies can be sent without a path resolver.
+func newReplyAddr(pkt *spkt.ScnPkt, lastHop *reliable.AppAddr) (*Addr, error) {
+	remote := &Addr{
+		IA: pkt.SrcIA,
+		// Copy the address to prevent races. See
+		// https://github.com/scionproto/scion/issues/1659.
+		Host: pkt.SrcHost.Copy(),
+	}
+	if lastHop == nil {
+		return remote, nil
+	}
+	// Copy the path, as the raw path of an intra-AS packet still points into
+	// the receive buffer.
+	path := pkt.Path.Copy()
+	if err := path.Reverse(); err != nil {
+		return nil, common.NewBasicError("Unable to reverse path on received packet", err)
 	}
+	remote.Path = path
+	// Copy the address to prevent races. See
+	// https://github.com/scionproto/scion/issues/1659.
+	remote.NextHopHost = lastHop.Addr.Copy()
+	remote.NextHopPort = lastHop.Port
+	return remote, nil
 }
 
-func (c *Conn) handleSCMPRev(hdr *scmp.Hdr, pkt *spkt.ScnPkt) {
-	scmpPayload, ok := pkt.Pld.(*scmp.Payload)
-	if !ok {
+// handleSCMP returns the error for the SCMP message in pkt, received from
+// src. Revocations and other path errors are reported to the path resolver,
+// and the SCMP handler of the Conn is called, if set.
+func (c *Conn) handleSCMP(hdr *scmp.Hdr, pkt *spkt.ScnPkt, src *Addr) *OpError {
+	var pld *scmp.Payload
+	if scmpPayload, ok := pkt.Pld.(*scmp.Payload); ok {
+		// Copy the payload, as its raw headers and info point into the
+		// receive buffer
+		if cpy, err := scmpPayload.Copy(); err != nil {
+			log.Error("Unable to copy SCMP payload", "err", err)
+		} else {
+			pld = cpy.(*scmp.Payload)
+			if pld.Info != nil {
+				pld.Info = pld.Info.Copy()
+			}
+		}
+	} else {
 		log.Error("Unable to type assert payload to SCMP payload", "type", common.TypeOf(pkt.Pld))
 	}
-	info, ok := scmpPayload.Info.(*scmp.InfoRevocation)
+	err := &OpError{scmp: hdr, pld: pld}
+	switch {
+	case err.Revoked():
+		c.handleSCMPRev(err)
+	case err.invalidatesPath():
+		c.handleSCMPPathError(err)
+	default:
+		log.Warn("Received SCMP message", "class", hdr.Class, "type", hdr.Type)
+	}
+	c.scmpMutex.Lock()
+	handler := c.scmpHandler
+	c.scmpMutex.Unlock()
+	if handler != nil {
+		handler(err, src)
+	}
+	return err
+}
+
+func (c *Conn) handleSCMPRev(e *OpError) {
+	if e.pld == nil {
+		return
+	}
+	info, ok := e.pld.Info.(*scmp.InfoRevocation)
 	if !ok {
 		log.Error("Unable to type assert SCMP Info to SCMP Revocation Info",
-			"type", common.TypeOf(scmpPayload.Info))
-	}
-	log.Info("Received SCMP revocation", "header", hdr.String(), "payload", scmpPayload.String())
-	// If we have a path manager, extract RevInfo buffer and send it
-	//
-	// FIXME(scrye): this completely hides the revocation from the application.
-	// This is problematic for applications that manage their own paths, as
-	// they need to be informed that they should try to use a different one.
+			"type", common.TypeOf(e.pld.Info))
+		return
+	}
+	log.Info("Received SCMP revocation", "header", e.scmp.String(), "payload", e.pld.String())
+	// If we have a path manager, extract RevInfo buffer and send it. The
+	// revocation is also returned to the application as an *OpError.
 	if c.scionNet.pathResolver != nil {
 		c.scionNet.pathResolver.Revoke(info.RawSRev)
 	}
 }
 
-// WriteToSCION sends b to raddr.
+// handleSCMPPathError removes the path of the packet that caused e from the
+// cache of the path resolver, such that the next packet to the same
+// destination is sent on a different path.
+func (c *Conn) handleSCMPPathError(e *OpError) {
+	log.Info("Received SCMP path error", "header", e.scmp.String())
+	if c.scionNet.pathResolver == nil {
+		return
+	}
+	dst, path := e.dstIA(), e.Path()
+	if dst.IsZero() || path == nil {
+		log.Warn("Unable to invalidate path, SCMP message does not contain it",
+			"header", e.scmp.String())
+		return
+	}
+	if c.scionNet.pathResolver.InvalidatePath(c.laddr.IA, dst, path) {
+		log.Info("Invalidated path after SCMP error", "dst", dst, "header", e.scmp.String())
+	}
+}
+
+// SCMPHandler is called for each SCMP message received by a Conn, with the
+// error returned by the read call and the address of the sender of the
+// message. Handlers are called by the goroutine that reads from the Conn, so
+// they must not block.
+type SCMPHandler func(err *OpError, src *Addr)
+
+// SetSCMPHandler sets the handler that is called for each SCMP message
+// received by c. If handler is nil, no handler is called.
+func (c *Conn) SetSCMPHandler(handler SCMPHandler) {
+	c.scmpMutex.Lock()
+	defer c.scmpMutex.Unlock()
+	c.scmpHandler = handler
+}
+
+// WriteToSCION sends b to raddr. If raddr contains a path and an overlay next
+// hop, e.g., because it was returned by ReadFromSCION, the packet is sent on
+// that path.
 func (c *Conn) WriteToSCION(b []byte, raddr *Addr) (int, error) {
 	if c.conn == nil {
 		return 0, common.NewBasicError("Connection not initialized", nil)
 	}
-	n, err := c.write(b, raddr)
+	n, err := c.write(b, raddr, nil)
+	if err != nil {
+		return 0, common.NewBasicError("Dispatcher error", err)
+	}
+	return n, err
+}
+
+// WriteToWithPath sends b to raddr on the path described by pathEntry, which
+// overrides any other path selection. The path must lead to the AS of raddr,
+// otherwise an error is returned. Like WriteTo, it must not be called on connections with a fixed remote
+// address.
+func (c *Conn) WriteToWithPath(b []byte, raddr *Addr,
+	pathEntry *sciond.PathReplyEntry) (int, error) {
+
+	if c.raddr != nil {
+		return 0, common.NewBasicError("Unable to WriteTo, remote address already set", nil)
+	}
+	if c.conn == nil {
+		return 0, common.NewBasicError("Connection not initialized", nil)
+	}
+	if pathEntry == nil {
+		return 0, common.NewBasicError("Unable to WriteTo, nil path", nil)
+	}
+	n, err := c.write(b, raddr, pathEntry)
 	if err != nil {
 		return 0, common.NewBasicError("Dispatcher error", err)
 	}
 	return n, err
 }
 
+// SetPath sets the path for all future packets to remote ASes, including
+// packets to addresses that contain a path, e.g., addresses returned by
+// ReadFrom. The path must lead to the AS of every packet sent, otherwise the
+// write fails; for connections that send to multiple ASes, use
+// SetPathSelector instead. Calling SetPath with nil restores the default path
+// selection.
+func (c *Conn) SetPath(pathEntry *sciond.PathReplyEntry) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	c.path = pathEntry
+}
+
+// SetPathSelector sets the selector that chooses the paths of future packets
+// to remote ASes, unless a path was set via SetPath. The selector is also
+// consulted for addresses that contain a path; if it returns nil, the path of
+// the address is used. Calling SetPathSelector with nil restores the default
+// path selection.
+func (c *Conn) SetPathSelector(selector PathSelector) {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	c.pathSelector = selector
+}
+
 func (c *Conn) WriteTo(b []byte, raddr net.Addr) (int, error) {
 	if c.raddr != nil {
 		return 0, common.NewBasicError("Unable to WriteTo, remote address already set", nil)
@@ -268,36 +404,15 @@ func (c *Conn) Write(b []byte) (int, error) {
 	return c.WriteToSCION(b, c.raddr)
 }
 
-func (c *Conn) write(b []byte, raddr *Addr) (int, error) {
+// write sends b to raddr. If pathEntry is not nil, the packet is sent on that
+// path. Otherwise, the path in raddr is used if it is set, and a path is
+// selected by the connection if it is not.
+func (c *Conn) write(b []byte, raddr *Addr, pathEntry *sciond.PathReplyEntry) (int, error) {
 	c.writeMutex.Lock()
 	defer c.writeMutex.Unlock()
-	var err error
-	var path *spath.Path
-	var nextHopHost addr.HostAddr
-	var nextHopPort uint16
-	// If src and dst are in the same AS, the path will be empty
-	if !c.laddr.IA.Eq(raddr.IA) {
-		if raddr.Path != nil && raddr.NextHopHost != nil && raddr.NextHopPort != 0 {
-			path = raddr.Path
-			nextHopHost = raddr.NextHopHost
-			nextHopPort = raddr.NextHopPort
-		} else {
-			if c.scionNet.pathResolver == nil {
-				return 0, common.NewBasicError("Path required, but no path manager configured", nil)
-			}
-
-			pathEntry, err := c.selectPathEntry(raddr)
-			if err != nil {
-				return 0, err
-			}
-			path = spath.New(pathEntry.Path.FwdPath)
-			nextHopHost = pathEntry.HostInfo.Host()
-			nextHopPort = pathEntry.HostInfo.Port
-			err = path.InitOffsets()
-			if err != nil {
-				return 0, common.NewBasicError("Unable to initialize path", err)
-			}
-		}
+	path, nextHopHost, nextHopPort, err := c.resolvePath(raddr, pathEntry)
+	if err != nil {
+		return 0, err
 	}
 
 	// Prepare packet fields
@@ -341,35 +456,108 @@ func (c *Conn) write(b []byte, raddr *Addr) (int, error) {
 	return pkt.Pld.Len(), nil
 }
 
-// selectPathEntry chooses a path to raddr. This must not be called if
-// running SCIOND-less.
+// resolvePath returns the path and overlay next hop for a packet to raddr. If
+// src and dst are in the same AS, the path is nil. Otherwise, pathEntry is
+// used if it is not nil, and the path chosen by selectPath otherwise. Path
+// entries that do not lead to the AS of raddr are rejected.
+func (c *Conn) resolvePath(raddr *Addr, pathEntry *sciond.PathReplyEntry) (*spath.Path,
+	addr.HostAddr, uint16, error) {
+
+	if c.laddr.IA.Eq(raddr.IA) {
+		return nil, nil, 0, nil
+	}
+	if pathEntry == nil {
+		var err error
+		if pathEntry, err = c.selectPath(raddr); err != nil {
+			return nil, nil, 0, err
+		}
+		if pathEntry == nil {
+			return raddr.Path, raddr.NextHopHost, raddr.NextHopPort, nil
+		}
+	}
+	if pathEntry.Path == nil {
+		return nil, nil, 0, common.NewBasicError("Path entry without path", nil)
+	}
+	if dst := pathEntry.Path.DstIA(); !dst.Eq(raddr.IA) {
+		return nil, nil, 0, common.NewBasicError("Path does not lead to destination AS", nil,
+			"pathDstIA", dst, "dstIA", raddr.IA)
+	}
+	path := spath.New(pathEntry.Path.FwdPath)
+	if err := path.InitOffsets(); err != nil {
+		return nil, nil, 0, common.NewBasicError("Unable to initialize path", err)
+	}
+	return path, pathEntry.HostInfo.Host(), pathEntry.HostInfo.Port, nil
+}
+
+// selectPath chooses the path to raddr. The path set via SetPath takes
+// precedence over the path selector, which takes precedence over the path
+// contained in raddr (e.g., the reply path of an address returned by
+// ReadFrom), which takes precedence over the paths of the path resolver. If
+// the path contained in raddr is chosen, nil is returned.
+func (c *Conn) selectPath(raddr *Addr) (*sciond.PathReplyEntry, error) {
+	if c.path != nil {
+		return c.path, nil
+	}
+	if c.pathSelector != nil {
+		pathEntry, err := c.pathSelector.SelectPath(raddr)
+		if err != nil || pathEntry != nil {
+			return pathEntry, err
+		}
+	}
+	if raddr.Path != nil && raddr.NextHopHost != nil && raddr.NextHopPort != 0 {
+		return nil, nil
+	}
+	if c.scionNet.pathResolver == nil {
+		return nil, common.NewBasicError("Path required, but no path manager configured", nil)
+	}
+	return c.selectPathEntry(raddr)
+}
+
+// selectPathEntry chooses a path to raddr. If the path used for the previous
+// packet to the remote AS is still available, it is chosen again. Otherwise,
+// the path ranked best by the path resolver is chosen. This must not be called
+// if running SCIOND-less.
 func (c *Conn) selectPathEntry(raddr *Addr) (*sciond.PathReplyEntry, error) {
-	var pathSet spathmeta.AppPathSet
 	if assert.On {
 		assert.Must(c.scionNet.pathResolver != nil, "must run with SCIOND for path selection")
 	}
-	// If the remote address is fixed, register source and destination for
-	// continous path updates
 	if c.raddr == nil {
-		pathSet = c.scionNet.pathResolver.Query(c.laddr.IA, raddr.IA)
-	} else {
-		pathSet = c.sp.Load().APS
+		// Use the path last used for the remote AS, so that packets to
+		// different remotes do not change each other's paths
+		var prefKey spathmeta.PathKey
+		if v, ok := c.prefPaths.Get(raddr.IA); ok {
+			prefKey = v.(spathmeta.PathKey)
+		}
+		path := c.scionNet.pathResolver.QueryPreferred(c.laddr.IA, raddr.IA, prefKey)
+		if path == nil {
+			return nil, common.NewBasicError("Path not found", nil,
+				"srcIA", c.laddr.IA, "dstIA", raddr.IA)
+		}
+		c.prefPaths.Add(raddr.IA, path.Key())
+		return path.Entry, nil
 	}
-
-	if len(pathSet) == 0 {
+	// The remote address is fixed, so the paths are continuously updated.
+	// Fall back to the most preferred path if the previous one is gone.
+	path := c.sp.Load().GetAppPath(c.prefPathKey)
+	if path == nil {
 		return nil, common.NewBasicError("Path not found", nil,
 			"srcIA", c.laddr.IA, "dstIA", raddr.IA)
 	}
-
-	// FIXME(scrye): A preferred path should be stored for each contacted
-	// destination. Currently the code below only supports one, which means
-	// that alternating sends between two remotes offers no guarantees on
-	// the path ever being the same.
-	path := pathSet.GetAppPath(c.prefPathKey)
 	c.prefPathKey = path.Key()
 	return path.Entry, nil
 }
 
+// newPrefPathCache returns a cache for the last used paths of an unconnected
+// Conn.
+func newPrefPathCache() *simplelru.LRU {
+	cache, err := simplelru.NewLRU(PrefPathCacheSize, nil)
+	if err != nil {
+		// Only fails for non-positive sizes
+		panic(err)
+	}
+	return cache
+}
+
 func (c *Conn) BindAddr() net.Addr {
 	return c.baddr
 }
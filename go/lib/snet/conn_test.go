@@ -0,0 +1,368 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snet
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/pathmgr"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/scmp"
+	"github.com/scionproto/scion/go/lib/sock/reliable"
+	"github.com/scionproto/scion/go/lib/spath"
+	"github.com/scionproto/scion/go/lib/spkt"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/lib/xtest/graph"
+)
+
+func TestSelectPathEntry(t *testing.T) {
+	Convey("Unconnected Conns should remember the last used path per remote AS", t, func() {
+		g := graph.NewDefaultGraph()
+		// Add a second link, so there are two paths to each destination
+		g.AddLink("1-ff00:0:133", 101902, "1-ff00:0:132", 191002, false)
+		pr, err := pathmgr.New(sciond.NewMockService(g),
+			&pathmgr.Timers{MaxAge: time.Millisecond}, log.Root())
+		xtest.FailOnErr(t, err)
+		localIA := xtest.MustParseIA("1-ff00:0:133")
+		conn := &Conn{
+			laddr:     &Addr{IA: localIA},
+			scionNet:  NewNetworkWithPR(localIA, "", pr),
+			prefPaths: newPrefPathCache(),
+		}
+		raddr131 := &Addr{IA: xtest.MustParseIA("1-ff00:0:131")}
+		raddr132 := &Addr{IA: xtest.MustParseIA("1-ff00:0:132")}
+		firstHop := func(raddr *Addr) common.IFIDType {
+			entry, err := conn.selectPathEntry(raddr)
+			xtest.FailOnErr(t, err)
+			return entry.Path.Interfaces[0].IfID
+		}
+		ifid := firstHop(raddr131)
+		SoMsg("same remote AS", firstHop(raddr131), ShouldEqual, ifid)
+		ifid132 := firstHop(raddr132)
+
+		// Take down the link of the selected path, so that 1-ff00:0:131
+		// switches to the other link
+		other := common.IFIDType(101902)
+		if ifid == other {
+			other = 1019
+		}
+		g.RemoveLink(ifid)
+		time.Sleep(5 * time.Millisecond)
+		SoMsg("switched", firstHop(raddr131), ShouldEqual, other)
+		// Restore the link; both remote ASes keep their paths
+		if ifid == 1019 {
+			g.AddLink("1-ff00:0:133", 1019, "1-ff00:0:132", 1910, false)
+		} else {
+			g.AddLink("1-ff00:0:133", 101902, "1-ff00:0:132", 191002, false)
+		}
+		time.Sleep(5 * time.Millisecond)
+		for i := 0; i < 3; i++ {
+			SoMsg("131 stable", firstHop(raddr131), ShouldEqual, other)
+			SoMsg("132 stable", firstHop(raddr132), ShouldEqual, ifid132)
+		}
+	})
+}
+
+func TestSelectPath(t *testing.T) {
+	Convey("Paths set by the application take precedence", t, func() {
+		localIA := xtest.MustParseIA("1-ff00:0:133")
+		conn := &Conn{
+			laddr:     &Addr{IA: localIA},
+			scionNet:  NewNetworkWithPR(localIA, "", nil),
+			prefPaths: newPrefPathCache(),
+		}
+		raddr := &Addr{IA: xtest.MustParseIA("1-ff00:0:131")}
+		selected := &sciond.PathReplyEntry{Path: &sciond.FwdPathMeta{Mtu: 1}}
+		pinned := &sciond.PathReplyEntry{Path: &sciond.FwdPathMeta{Mtu: 2}}
+		var selectorAddr *Addr
+		selector := PathSelectorFunc(func(a *Addr) (*sciond.PathReplyEntry, error) {
+			selectorAddr = a
+			if a.IA.Eq(raddr.IA) {
+				return selected, nil
+			}
+			return nil, nil
+		})
+
+		_, err := conn.selectPath(raddr)
+		SoMsg("no resolver", err, ShouldNotBeNil)
+		conn.SetPathSelector(selector)
+		entry, err := conn.selectPath(raddr)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("selector", entry, ShouldEqual, selected)
+		SoMsg("selector addr", selectorAddr, ShouldEqual, raddr)
+		_, err = conn.selectPath(&Addr{IA: xtest.MustParseIA("1-ff00:0:132")})
+		SoMsg("selector fallback", err, ShouldNotBeNil)
+		conn.SetPath(pinned)
+		entry, err = conn.selectPath(raddr)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("pinned", entry, ShouldEqual, pinned)
+		conn.SetPath(nil)
+		entry, _ = conn.selectPath(raddr)
+		SoMsg("unpinned", entry, ShouldEqual, selected)
+		conn.SetPathSelector(nil)
+		_, err = conn.selectPath(raddr)
+		SoMsg("default", err, ShouldNotBeNil)
+	})
+	Convey("Paths set by the application take precedence over reply paths", t, func() {
+		localIA := xtest.MustParseIA("1-ff00:0:133")
+		conn := &Conn{
+			laddr:     &Addr{IA: localIA},
+			scionNet:  NewNetworkWithPR(localIA, "", nil),
+			prefPaths: newPrefPathCache(),
+		}
+		replyAddr := &Addr{
+			IA:          xtest.MustParseIA("1-ff00:0:131"),
+			Path:        spath.New(make(common.RawBytes, spath.InfoFieldLength)),
+			NextHopHost: addr.HostFromIP(net.IPv4(10, 0, 0, 1)),
+			NextHopPort: 30041,
+		}
+		selected := &sciond.PathReplyEntry{Path: &sciond.FwdPathMeta{Mtu: 1}}
+		pinned := &sciond.PathReplyEntry{Path: &sciond.FwdPathMeta{Mtu: 2}}
+
+		entry, err := conn.selectPath(replyAddr)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("reply path", entry, ShouldBeNil)
+		conn.SetPathSelector(PathSelectorFunc(
+			func(a *Addr) (*sciond.PathReplyEntry, error) {
+				return selected, nil
+			},
+		))
+		entry, err = conn.selectPath(replyAddr)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("selector", entry, ShouldEqual, selected)
+		conn.SetPath(pinned)
+		entry, err = conn.selectPath(replyAddr)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("pinned", entry, ShouldEqual, pinned)
+	})
+}
+
+func TestResolvePathDst(t *testing.T) {
+	Convey("Paths set by the application must lead to the destination AS", t, func() {
+		localIA := xtest.MustParseIA("1-ff00:0:133")
+		conn := &Conn{
+			laddr:     &Addr{IA: localIA},
+			scionNet:  NewNetworkWithPR(localIA, "", nil),
+			prefPaths: newPrefPathCache(),
+		}
+		// Single segment with a single hop field
+		raw := make(common.RawBytes, spath.InfoFieldLength+spath.HopFieldLength)
+		(&spath.InfoField{ConsDir: true, Hops: 1}).Write(raw)
+		pathEntry := &sciond.PathReplyEntry{
+			Path: &sciond.FwdPathMeta{
+				FwdPath: raw,
+				Interfaces: []sciond.PathInterface{
+					{RawIsdas: localIA.IAInt(), IfID: 1019},
+					{RawIsdas: xtest.MustParseIA("1-ff00:0:132").IAInt(), IfID: 1910},
+				},
+			},
+		}
+		host := addr.HostFromIP(net.IPv4(192, 168, 0, 1))
+		raddr := &Addr{IA: xtest.MustParseIA("1-ff00:0:132"), Host: host}
+
+		path, _, _, err := conn.resolvePath(raddr, pathEntry)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("path", path, ShouldNotBeNil)
+		_, _, _, err = conn.resolvePath(&Addr{IA: xtest.MustParseIA("1-ff00:0:131"),
+			Host: host}, pathEntry)
+		SoMsg("other AS", err, ShouldNotBeNil)
+		conn.SetPath(pathEntry)
+		_, _, _, err = conn.resolvePath(raddr, nil)
+		SoMsg("pinned", err, ShouldBeNil)
+		_, _, _, err = conn.resolvePath(&Addr{IA: xtest.MustParseIA("1-ff00:0:131"),
+			Host: host}, nil)
+		SoMsg("pinned other AS", err, ShouldNotBeNil)
+	})
+}
+
+func TestReplyAddr(t *testing.T) {
+	Convey("Replies should be sent on the reversed path of the received packet", t, func() {
+		localIA := xtest.MustParseIA("1-ff00:0:133")
+		conn := &Conn{
+			laddr:     &Addr{IA: localIA},
+			scionNet:  NewNetworkWithPR(localIA, "", nil),
+			prefPaths: newPrefPathCache(),
+		}
+		// Single segment with hop fields 1 and 2, as received by the last AS
+		raw := make(common.RawBytes, spath.InfoFieldLength+2*spath.HopFieldLength)
+		(&spath.InfoField{ConsDir: true, Hops: 2}).Write(raw)
+		for i := 0; i < spath.HopFieldLength; i++ {
+			raw[spath.InfoFieldLength+i] = 1
+			raw[spath.InfoFieldLength+spath.HopFieldLength+i] = 2
+		}
+		pkt := &spkt.ScnPkt{
+			SrcIA:   xtest.MustParseIA("1-ff00:0:131"),
+			SrcHost: addr.HostFromIP(net.IPv4(192, 168, 0, 1)),
+			Path: &spath.Path{
+				Raw:    raw,
+				HopOff: spath.InfoFieldLength + spath.HopFieldLength,
+			},
+		}
+		lastHop := &reliable.AppAddr{Addr: addr.HostFromIP(net.IPv4(10, 0, 0, 1)), Port: 30041}
+
+		remote, err := newReplyAddr(pkt, nil)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("ia", remote.IA, ShouldResemble, pkt.SrcIA)
+		SoMsg("host", remote.Host, ShouldResemble, pkt.SrcHost)
+		SoMsg("no path", remote.Path, ShouldBeNil)
+
+		remote, err = newReplyAddr(pkt, lastHop)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("next hop host", remote.NextHopHost, ShouldResemble, lastHop.Addr)
+		SoMsg("next hop port", remote.NextHopPort, ShouldEqual, lastHop.Port)
+		SoMsg("hop offset", remote.Path.HopOff, ShouldEqual, spath.InfoFieldLength)
+		infoF, err := spath.InfoFFromRaw(remote.Path.Raw)
+		xtest.FailOnErr(t, err)
+		SoMsg("cons dir", infoF.ConsDir, ShouldBeFalse)
+		SoMsg("first hop", remote.Path.Raw[spath.InfoFieldLength], ShouldEqual, 2)
+		SoMsg("received path", pkt.Path.Raw[spath.InfoFieldLength], ShouldEqual, 1)
+
+		path, nextHopHost, nextHopPort, err := conn.resolvePath(remote, nil)
+		SoMsg("err", err, ShouldBeNil)
+		SoMsg("path", path, ShouldEqual, remote.Path)
+		SoMsg("resolved next hop host", nextHopHost, ShouldResemble, remote.NextHopHost)
+		SoMsg("resolved next hop port", nextHopPort, ShouldEqual, remote.NextHopPort)
+		_, _, _, err = conn.resolvePath(&Addr{IA: remote.IA, Host: remote.Host}, nil)
+		SoMsg("without path", err, ShouldNotBeNil)
+	})
+}
+
+func TestHandleSCMP(t *testing.T) {
+	Convey("SCMP messages should be returned as typed errors", t, func() {
+		g := graph.NewDefaultGraph()
+		g.AddLink("1-ff00:0:133", 101902, "1-ff00:0:132", 191002, false)
+		pr, err := pathmgr.New(sciond.NewMockService(g), nil, log.Root())
+		xtest.FailOnErr(t, err)
+		localIA := xtest.MustParseIA("1-ff00:0:133")
+		dstIA := xtest.MustParseIA("1-ff00:0:131")
+		conn := &Conn{
+			laddr:     &Addr{IA: localIA},
+			scionNet:  NewNetworkWithPR(localIA, "", pr),
+			prefPaths: newPrefPathCache(),
+		}
+		var handled []*OpError
+		conn.SetSCMPHandler(func(err *OpError, src *Addr) {
+			handled = append(handled, err)
+		})
+		aps := pr.Query(localIA, dstIA)
+		SoMsg("paths", len(aps), ShouldEqual, 2)
+		// Paths of the mock service have no forwarding path, so add distinct ones
+		var i byte
+		for _, ap := range aps {
+			i++
+			ap.Entry.Path.FwdPath = []byte{i}
+		}
+		ap := aps.GetAppPath("")
+		addrHdr := make(common.RawBytes, 2*addr.IABytes)
+		dstIA.Write(addrHdr)
+		localIA.Write(addrHdr[addr.IABytes:])
+		newPkt := func(ct scmp.ClassType, info scmp.Info) *spkt.ScnPkt {
+			return &spkt.ScnPkt{
+				SrcIA: xtest.MustParseIA("1-ff00:0:132"),
+				L4:    scmp.NewHdr(ct, 0),
+				Pld: &scmp.Payload{
+					Meta:    &scmp.Meta{},
+					Info:    info,
+					AddrHdr: addrHdr,
+					PathHdr: ap.Entry.Path.FwdPath,
+				},
+			}
+		}
+		handle := func(pkt *spkt.ScnPkt) *OpError {
+			return conn.handleSCMP(pkt.L4.(*scmp.Hdr), pkt, &Addr{IA: pkt.SrcIA})
+		}
+		// classes returns the results of the class checks of e, in the order
+		// ROUTING, CMNHDR, PATH, EXT.
+		classes := func(e *OpError) []bool {
+			return []bool{e.IsRoutingError(), e.IsCmnHdrError(), e.IsPathError(), e.IsExtError()}
+		}
+
+		Convey("Path errors invalidate the path", func() {
+			err := handle(newPkt(scmp.ClassType{Class: scmp.C_Path, Type: scmp.T_P_ExpiredHopF},
+				&scmp.InfoPathOffsets{}))
+			SoMsg("class", classes(err), ShouldResemble, []bool{false, false, true, false})
+			SoMsg("type", err.Type(), ShouldEqual, scmp.T_P_ExpiredHopF)
+			SoMsg("revoked", err.Revoked(), ShouldBeFalse)
+			SoMsg("path", []byte(err.Path()), ShouldResemble, ap.Entry.Path.FwdPath)
+			SoMsg("handler", handled, ShouldResemble, []*OpError{err})
+			remaining := pr.Query(localIA, dstIA)
+			SoMsg("remaining", len(remaining), ShouldEqual, 1)
+			SoMsg("invalidated", remaining[ap.Key()], ShouldBeNil)
+		})
+		Convey("Bad MACs are distinguished from expired hop fields", func() {
+			err := handle(newPkt(scmp.ClassType{Class: scmp.C_Path, Type: scmp.T_P_BadMac},
+				&scmp.InfoPathOffsets{}))
+			SoMsg("class", classes(err), ShouldResemble, []bool{false, false, true, false})
+			SoMsg("type", err.Type(), ShouldEqual, scmp.T_P_BadMac)
+			SoMsg("invalidated", pr.Query(localIA, dstIA)[ap.Key()], ShouldBeNil)
+		})
+		Convey("Routing errors contain the MTU", func() {
+			err := handle(newPkt(scmp.ClassType{Class: scmp.C_Routing, Type: scmp.T_R_OversizePkt},
+				&scmp.InfoPktSize{Size: 1500, MTU: 1472}))
+			SoMsg("class", classes(err), ShouldResemble, []bool{true, false, false, false})
+			SoMsg("type", err.Type(), ShouldEqual, scmp.T_R_OversizePkt)
+			SoMsg("mtu", err.MTU(), ShouldEqual, 1472)
+			SoMsg("revoked", err.Revoked(), ShouldBeFalse)
+			SoMsg("paths", len(pr.Query(localIA, dstIA)), ShouldEqual, 2)
+		})
+		Convey("Common header errors are reported", func() {
+			err := handle(newPkt(scmp.ClassType{Class: scmp.C_CmnHdr, Type: scmp.T_C_BadVersion},
+				nil))
+			SoMsg("class", classes(err), ShouldResemble, []bool{false, true, false, false})
+			SoMsg("type", err.Type(), ShouldEqual, scmp.T_C_BadVersion)
+			SoMsg("paths", len(pr.Query(localIA, dstIA)), ShouldEqual, 2)
+		})
+		Convey("Extension errors are reported", func() {
+			err := handle(newPkt(scmp.ClassType{Class: scmp.C_Ext, Type: scmp.T_E_BadHopByHop},
+				&scmp.InfoExtIdx{}))
+			SoMsg("class", classes(err), ShouldResemble, []bool{false, false, false, true})
+			SoMsg("type", err.Type(), ShouldEqual, scmp.T_E_BadHopByHop)
+			SoMsg("paths", len(pr.Query(localIA, dstIA)), ShouldEqual, 2)
+		})
+		Convey("Revocations do not alias the receive buffer", func() {
+			rawSRev := common.RawBytes{1, 2, 3, 4}
+			err := handle(newPkt(scmp.ClassType{Class: scmp.C_Path, Type: scmp.T_P_RevokedIF},
+				&scmp.InfoRevocation{InfoPathOffsets: &scmp.InfoPathOffsets{},
+					RawSRev: rawSRev}))
+			// Simulate the receive buffer being reused by the next read
+			copy(rawSRev, common.RawBytes{0, 0, 0, 0})
+			SoMsg("revoked", err.Revoked(), ShouldBeTrue)
+			info, ok := err.Payload().Info.(*scmp.InfoRevocation)
+			SoMsg("info", ok, ShouldBeTrue)
+			SoMsg("raw rev", info.RawSRev, ShouldResemble, common.RawBytes{1, 2, 3, 4})
+			SoMsg("paths", len(pr.Query(localIA, dstIA)), ShouldEqual, 2)
+		})
+		Convey("Other classes are returned as OpError", func() {
+			var err error = handle(newPkt(
+				scmp.ClassType{Class: scmp.C_General, Type: scmp.T_G_EchoReply}, &scmp.InfoEcho{}))
+			opErr, ok := err.(*OpError)
+			SoMsg("type", ok, ShouldBeTrue)
+			SoMsg("class", classes(opErr), ShouldResemble, []bool{false, false, false, false})
+			SoMsg("mtu", opErr.MTU(), ShouldEqual, 0)
+			SoMsg("revoked", opErr.Revoked(), ShouldBeFalse)
+			conn.SetSCMPHandler(nil)
+			handle(newPkt(scmp.ClassType{Class: scmp.C_Ext, Type: scmp.T_E_BadHopByHop},
+				&scmp.InfoExtIdx{}))
+			SoMsg("handler removed", len(handled), ShouldEqual, 1)
+		})
+	})
+}
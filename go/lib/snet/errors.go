@@ -0,0 +1,134 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snet
+
+import (
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/scmp"
+)
+
+// Error is implemented by all errors returned by Read calls because an SCMP
+// message was received.
+type Error interface {
+	error
+	SCMP() *scmp.Hdr
+}
+
+var _ Error = (*OpError)(nil)
+
+// OpError is returned by Read calls if an SCMP message was received. The
+// class of the message can be checked via IsRoutingError, IsCmnHdrError,
+// IsPathError and IsExtError, and the exact error via Type. Class-specific
+// information is available via MTU (ROUTING), and Revoked and Path (PATH).
+type OpError struct {
+	scmp *scmp.Hdr
+	pld  *scmp.Payload
+}
+
+func (e *OpError) SCMP() *scmp.Hdr {
+	return e.scmp
+}
+
+// Class returns the class of the SCMP message.
+func (e *OpError) Class() scmp.Class {
+	return e.scmp.Class
+}
+
+// Type returns the type of the SCMP message within its class, e.g.,
+// scmp.T_P_BadMac for an error of class PATH.
+func (e *OpError) Type() scmp.Type {
+	return e.scmp.Type
+}
+
+// IsRoutingError returns true if the SCMP message is of class ROUTING, e.g.,
+// because the destination host is unreachable or a packet exceeded the MTU.
+func (e *OpError) IsRoutingError() bool {
+	return e.scmp.Class == scmp.C_Routing
+}
+
+// IsCmnHdrError returns true if the SCMP message is of class CMNHDR, i.e., the
+// common header of a sent packet was rejected.
+func (e *OpError) IsCmnHdrError() bool {
+	return e.scmp.Class == scmp.C_CmnHdr
+}
+
+// IsPathError returns true if the SCMP message is of class PATH, e.g., because
+// a hop field of the path expired, its MAC was invalid, or an interface on
+// the path was revoked. If the Conn uses a path resolver, the path is removed
+// from the resolver's cache, so that subsequent writes use a different path.
+func (e *OpError) IsPathError() bool {
+	return e.scmp.Class == scmp.C_Path
+}
+
+// IsExtError returns true if the SCMP message is of class EXT, i.e., an
+// extension header of a sent packet was rejected.
+func (e *OpError) IsExtError() bool {
+	return e.scmp.Class == scmp.C_Ext
+}
+
+// Payload returns the payload of the SCMP message, or nil if it could not be
+// parsed.
+func (e *OpError) Payload() *scmp.Payload {
+	return e.pld
+}
+
+// MTU returns the MTU reported by an OVERSIZE_PKT error, or 0 if the error
+// does not contain an MTU.
+func (e *OpError) MTU() uint16 {
+	if e.pld == nil {
+		return 0
+	}
+	if info, ok := e.pld.Info.(*scmp.InfoPktSize); ok {
+		return info.MTU
+	}
+	return 0
+}
+
+// Revoked returns true if the error reports a revoked interface.
+func (e *OpError) Revoked() bool {
+	return e.IsPathError() && e.scmp.Type == scmp.T_P_RevokedIF
+}
+
+// Path returns the forwarding path of the packet that caused the error, or
+// nil if it is not contained in the SCMP message.
+func (e *OpError) Path() common.RawBytes {
+	if e.pld == nil || len(e.pld.PathHdr) == 0 {
+		return nil
+	}
+	return e.pld.PathHdr
+}
+
+func (e *OpError) Error() string {
+	return e.scmp.String()
+}
+
+// invalidatesPath returns true if the error is of class PATH, and the path of
+// the packet that caused it must not be used anymore. Revocations are
+// excluded, as they are handled by the path resolver for all paths containing
+// the interface.
+func (e *OpError) invalidatesPath() bool {
+	return e.IsPathError() && e.scmp.Type != scmp.T_P_PathRequired &&
+		e.scmp.Type != scmp.T_P_RevokedIF
+}
+
+// dstIA returns the destination AS of the packet that caused the error, or
+// the zero value if it is not contained in the SCMP message.
+func (e *OpError) dstIA() addr.IA {
+	if e.pld == nil || len(e.pld.AddrHdr) < addr.IABytes {
+		return addr.IA{}
+	}
+	return addr.IAFromRaw(e.pld.AddrHdr)
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package multipath implements a net.PacketConn that sends datagrams to a
+// remote AS across all available paths, instead of a single one.
+//
+// The paths are taken from a pathmgr.SyncPaths watch, so path changes and
+// revocations are picked up automatically. Each path gets a share of the
+// datagrams that is inversely proportional to its RTT and proportional to its
+// delivery rate; paths that are down are not used. RTT, loss and whether a
+// path is down are measured by a pathmgr.Prober, which must be running for
+// the path resolver to shift load away from degraded paths. Without probing
+// results, datagrams are striped evenly across all paths.
+//
+// Since Conn implements net.PacketConn, it can be used as the underlying
+// connection of a QUIC session, e.g.:
+//   conn, err := multipath.Listen(nil, laddr, raddr.IA)
+//   sess, err := quic.Dial(conn, raddr, "host:0", tlsConfig, nil)
+package multipath
+
+import (
+	"net"
+	"sync"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/pathmgr"
+	"github.com/scionproto/scion/go/lib/snet"
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+)
+
+var _ net.PacketConn = (*Conn)(nil)
+
+// PathStats contains the number of datagrams and bytes sent on a path.
+type PathStats struct {
+	Datagrams uint64
+	Bytes     uint64
+}
+
+// Conn is a net.PacketConn that stripes datagrams to hosts in a remote AS
+// across multiple paths, including replies to the addresses returned by
+// ReadFrom. Datagrams to other ASes are sent as by the underlying snet.Conn.
+// Reads are passed through to the underlying snet.Conn.
+type Conn struct {
+	*snet.Conn
+	src addr.IA
+	dst addr.IA
+	sp  *pathmgr.SyncPaths
+	// If not nil, the watch is removed from pr when the Conn is closed
+	pr *pathmgr.PR
+
+	mutex sync.Mutex
+	sched scheduler
+	stats map[spathmeta.PathKey]PathStats
+}
+
+// New returns a Conn that sends datagrams to hosts in AS dst via conn, across
+// the paths in sp. Conn must not have a fixed remote address, and sp must
+// contain the paths from the local AS of conn to dst.
+func New(conn *snet.Conn, dst addr.IA, sp *pathmgr.SyncPaths) *Conn {
+	return &Conn{
+		Conn:  conn,
+		src:   conn.LocalSnetAddr().IA,
+		dst:   dst,
+		sp:    sp,
+		stats: make(map[spathmeta.PathKey]PathStats),
+	}
+}
+
+// Listen registers laddr with the dispatcher of network, watches the paths
+// to dst, and returns a Conn that stripes datagrams to hosts in dst across
+// those paths. If network is nil, the default networking context is used. The
+// network must have a path resolver.
+func Listen(network *snet.Network, laddr *snet.Addr, dst addr.IA) (*Conn, error) {
+	if network == nil {
+		network = snet.DefNetwork
+	}
+	if network == nil {
+		return nil, common.NewBasicError("SCION network not initialized", nil)
+	}
+	pr := network.PathResolver()
+	if pr == nil {
+		return nil, common.NewBasicError("Multipath requires a path resolver", nil)
+	}
+	conn, err := network.ListenSCION("udp4", laddr)
+	if err != nil {
+		return nil, err
+	}
+	sp, err := pr.Watch(conn.LocalSnetAddr().IA, dst)
+	if err != nil {
+		conn.Close()
+		return nil, common.NewBasicError("Unable to watch paths", err, "dst", dst)
+	}
+	c := New(conn, dst, sp)
+	c.pr = pr
+	return c, nil
+}
+
+// WriteTo sends b to a. If a is in the remote AS of c, the path is chosen by
+// the scheduler. Datagrams within the local AS do not need a path.
+func (c *Conn) WriteTo(b []byte, a net.Addr) (int, error) {
+	raddr, ok := a.(*snet.Addr)
+	if !ok {
+		return 0, common.NewBasicError("Unable to write to non-SCION address", nil, "addr", a)
+	}
+	ap, err := c.pathTo(raddr, len(b))
+	if err != nil {
+		return 0, err
+	}
+	if ap == nil {
+		return c.Conn.WriteToSCION(b, raddr)
+	}
+	return c.Conn.WriteToWithPath(b, raddr, ap.Entry)
+}
+
+// pathTo returns the path chosen by the scheduler for a datagram of n bytes
+// to raddr, or nil if the datagram is sent as by the underlying snet.Conn.
+// Datagrams to hosts in the remote AS are striped even if raddr contains a
+// path, e.g., the reply path of an address returned by ReadFrom; the path of
+// raddr is only used if no watched path is available.
+func (c *Conn) pathTo(raddr *snet.Addr, n int) (*spathmeta.AppPath, error) {
+	if !raddr.IA.Eq(c.dst) || raddr.IA.Eq(c.src) {
+		return nil, nil
+	}
+	ap := c.nextPath(n)
+	if ap == nil && raddr.Path == nil {
+		return nil, common.NewBasicError("Path not found", nil, "dstIA", c.dst)
+	}
+	return ap, nil
+}
+
+// WriteToSCION is the same as WriteTo.
+func (c *Conn) WriteToSCION(b []byte, raddr *snet.Addr) (int, error) {
+	return c.WriteTo(b, raddr)
+}
+
+// nextPath returns the path for the next datagram, and accounts n bytes to
+// it.
+func (c *Conn) nextPath(n int) *spathmeta.AppPath {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ap := c.sched.next(c.sp.Load())
+	if ap != nil {
+		s := c.stats[ap.Key()]
+		s.Datagrams++
+		s.Bytes += uint64(n)
+		c.stats[ap.Key()] = s
+	}
+	return ap
+}
+
+// Stats returns the number of datagrams and bytes sent on each path since the
+// Conn was created.
+func (c *Conn) Stats() map[spathmeta.PathKey]PathStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	stats := make(map[spathmeta.PathKey]PathStats, len(c.stats))
+	for k, v := range c.stats {
+		stats[k] = v
+	}
+	return stats
+}
+
+// Close closes the underlying snet.Conn. If the Conn was created by Listen,
+// the path watch is removed as well.
+func (c *Conn) Close() error {
+	if c.pr != nil {
+		if err := c.pr.Unwatch(c.src, c.dst); err != nil {
+			c.Conn.Close()
+			return err
+		}
+	}
+	return c.Conn.Close()
+}
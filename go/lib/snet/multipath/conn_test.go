@@ -0,0 +1,103 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multipath
+
+import (
+	"net"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/log"
+	"github.com/scionproto/scion/go/lib/pathmgr"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/snet"
+	"github.com/scionproto/scion/go/lib/spath"
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+	"github.com/scionproto/scion/go/lib/xtest"
+	"github.com/scionproto/scion/go/lib/xtest/graph"
+)
+
+var (
+	srcIA = xtest.MustParseIA("1-ff00:0:133")
+	dstIA = xtest.MustParseIA("1-ff00:0:131")
+)
+
+// newTestConn returns a Conn without underlying snet.Conn, that stripes
+// datagrams from srcIA to dstIA across the two paths of the default graph
+// with an additional link between 133 and 132.
+func newTestConn(t *testing.T) *Conn {
+	t.Helper()
+	g := graph.NewDefaultGraph()
+	g.AddLink("1-ff00:0:133", 101902, "1-ff00:0:132", 191002, false)
+	pr, err := pathmgr.New(sciond.NewMockService(g), nil, log.Root())
+	xtest.FailOnErr(t, err)
+	sp, err := pr.Watch(srcIA, dstIA)
+	xtest.FailOnErr(t, err)
+	return &Conn{
+		src:   srcIA,
+		dst:   dstIA,
+		sp:    sp,
+		stats: make(map[spathmeta.PathKey]PathStats),
+	}
+}
+
+func TestConnStats(t *testing.T) {
+	Convey("Datagrams should be striped across the watched paths", t, func() {
+		c := newTestConn(t)
+		for i := 0; i < 4; i++ {
+			SoMsg("path", c.nextPath(100), ShouldNotBeNil)
+		}
+		stats := c.Stats()
+		SoMsg("paths", len(stats), ShouldEqual, 2)
+		for _, ap := range c.sp.Load().APS {
+			SoMsg("datagrams", stats[ap.Key()].Datagrams, ShouldEqual, 2)
+			SoMsg("bytes", stats[ap.Key()].Bytes, ShouldEqual, 200)
+		}
+	})
+}
+
+func TestConnPathTo(t *testing.T) {
+	Convey("Paths are chosen by the scheduler for hosts in the remote AS", t, func() {
+		c := newTestConn(t)
+		host := addr.HostFromIP(net.IPv4(192, 168, 0, 1))
+		Convey("Replies to addresses returned by ReadFrom are striped", func() {
+			// Addresses returned by ReadFrom contain the reversed path of
+			// the received datagram.
+			raddr := &snet.Addr{IA: dstIA, Host: host, L4Port: 40000,
+				Path: spath.New(common.RawBytes{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08})}
+			for i := 0; i < 4; i++ {
+				ap, err := c.pathTo(raddr, 100)
+				SoMsg("err", err, ShouldBeNil)
+				SoMsg("path", ap, ShouldNotBeNil)
+			}
+			stats := c.Stats()
+			SoMsg("paths", len(stats), ShouldEqual, 2)
+			for _, ap := range c.sp.Load().APS {
+				SoMsg("datagrams", stats[ap.Key()].Datagrams, ShouldEqual, 2)
+			}
+		})
+		Convey("Datagrams to other ASes are sent without a chosen path", func() {
+			for _, ia := range []addr.IA{srcIA, xtest.MustParseIA("1-ff00:0:132")} {
+				ap, err := c.pathTo(&snet.Addr{IA: ia, Host: host, L4Port: 40000}, 100)
+				SoMsg("err", err, ShouldBeNil)
+				SoMsg("path", ap, ShouldBeNil)
+			}
+			SoMsg("stats", c.Stats(), ShouldBeEmpty)
+		})
+	})
+}
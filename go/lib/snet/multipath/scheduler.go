@@ -0,0 +1,105 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multipath
+
+import (
+	"time"
+
+	"github.com/scionproto/scion/go/lib/pathmgr"
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+)
+
+// scheduler distributes datagrams across paths using smooth weighted round
+// robin, i.e., each path gets a share of the datagrams proportional to its
+// weight, and datagrams on the same path are spread out as evenly as
+// possible.
+type scheduler struct {
+	// Path data the weights were computed from
+	data    *pathmgr.SyncPathsData
+	paths   []*spathmeta.AppPath
+	weights []float64
+	current []float64
+}
+
+// next returns the path for the next datagram, based on data. If data does
+// not contain any paths, nil is returned.
+func (s *scheduler) next(data *pathmgr.SyncPathsData) *spathmeta.AppPath {
+	if data != s.data {
+		s.reset(data)
+	}
+	if len(s.paths) == 0 {
+		return nil
+	}
+	var total float64
+	best := 0
+	for i := range s.paths {
+		s.current[i] += s.weights[i]
+		total += s.weights[i]
+		if s.current[i] > s.current[best] {
+			best = i
+		}
+	}
+	s.current[best] -= total
+	return s.paths[best]
+}
+
+// reset recomputes the weights of the paths in data.
+func (s *scheduler) reset(data *pathmgr.SyncPathsData) {
+	s.data = data
+	s.paths = data.Ranked
+	s.weights = weights(data)
+	s.current = make([]float64, len(s.paths))
+}
+
+// weights computes the weight of each path in data.Ranked. The weight of a
+// path is inversely proportional to its RTT, and proportional to the fraction
+// of probes that were not lost. Paths that are down get weight 0. If no path
+// has a positive weight (e.g., all paths are down), all paths get the same
+// weight. Paths without probing results are assumed to have the average RTT
+// of the other paths.
+func weights(data *pathmgr.SyncPathsData) []float64 {
+	var sumRTT time.Duration
+	var numRTT int
+	for _, ap := range data.Ranked {
+		if h := data.Health[ap.Key()]; h.RTT > 0 {
+			sumRTT += h.RTT
+			numRTT++
+		}
+	}
+	defRTT := time.Second
+	if numRTT > 0 {
+		defRTT = sumRTT / time.Duration(numRTT)
+	}
+	w := make([]float64, len(data.Ranked))
+	var total float64
+	for i, ap := range data.Ranked {
+		h := data.Health[ap.Key()]
+		if h.Down {
+			continue
+		}
+		rtt := h.RTT
+		if rtt <= 0 {
+			rtt = defRTT
+		}
+		w[i] = (1 - h.Loss()) / rtt.Seconds()
+		total += w[i]
+	}
+	if total == 0 {
+		for i := range w {
+			w[i] = 1
+		}
+	}
+	return w
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multipath
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/pathmgr"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/spath/spathmeta"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+// newTestData returns path data with paths "a" and "b", and the given health
+// of the paths.
+func newTestData(health map[string]pathmgr.PathHealth) (*pathmgr.SyncPathsData,
+	map[string]*spathmeta.AppPath) {
+
+	newEntry := func(ifid common.IFIDType) *sciond.PathReplyEntry {
+		return &sciond.PathReplyEntry{
+			Path: &sciond.FwdPathMeta{
+				Interfaces: []sciond.PathInterface{
+					{RawIsdas: xtest.MustParseIA("1-ff00:0:110").IAInt(), IfID: ifid},
+				},
+			},
+		}
+	}
+	aps := make(spathmeta.AppPathSet)
+	paths := map[string]*spathmeta.AppPath{
+		"a": aps.Add(newEntry(1)),
+		"b": aps.Add(newEntry(2)),
+	}
+	data := &pathmgr.SyncPathsData{
+		APS:    aps,
+		Ranked: pathmgr.Rank(aps, nil),
+		Health: make(map[spathmeta.PathKey]pathmgr.PathHealth),
+	}
+	for name, h := range health {
+		data.Health[paths[name].Key()] = h
+	}
+	return data, paths
+}
+
+// countPaths returns how often each path is chosen by s in n datagrams.
+func countPaths(s *scheduler, data *pathmgr.SyncPathsData,
+	paths map[string]*spathmeta.AppPath, n int) map[string]int {
+
+	names := make(map[*spathmeta.AppPath]string)
+	for name, ap := range paths {
+		names[ap] = name
+	}
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[names[s.next(data)]]++
+	}
+	return counts
+}
+
+func TestScheduler(t *testing.T) {
+	ms := time.Millisecond
+	testCases := []struct {
+		Name     string
+		Health   map[string]pathmgr.PathHealth
+		Expected map[string]int
+	}{
+		{"no probing results", nil, map[string]int{"a": 50, "b": 50}},
+		{"rtt", map[string]pathmgr.PathHealth{
+			"a": {RTT: 10 * ms}, "b": {RTT: 30 * ms},
+		}, map[string]int{"a": 75, "b": 25}},
+		{"loss", map[string]pathmgr.PathHealth{
+			"a": {RTT: 10 * ms, Sent: 4, Lost: 2}, "b": {RTT: 10 * ms},
+		}, map[string]int{"a": 33, "b": 67}},
+		{"unknown rtt", map[string]pathmgr.PathHealth{
+			"a": {RTT: 10 * ms},
+		}, map[string]int{"a": 50, "b": 50}},
+		{"down", map[string]pathmgr.PathHealth{
+			"a": {RTT: 10 * ms, Down: true}, "b": {RTT: 30 * ms},
+		}, map[string]int{"b": 100}},
+		{"all down", map[string]pathmgr.PathHealth{
+			"a": {Down: true}, "b": {Down: true},
+		}, map[string]int{"a": 50, "b": 50}},
+	}
+	Convey("Datagrams should be distributed according to path health", t, func() {
+		for _, tc := range testCases {
+			Convey(tc.Name, func() {
+				data, paths := newTestData(tc.Health)
+				counts := countPaths(&scheduler{}, data, paths, 100)
+				for name, expected := range tc.Expected {
+					SoMsg(name, counts[name], ShouldAlmostEqual, expected, 1)
+				}
+				SoMsg("paths", len(counts), ShouldEqual, len(tc.Expected))
+			})
+		}
+	})
+	Convey("Datagrams should be spread out evenly", t, func() {
+		data, _ := newTestData(nil)
+		s := &scheduler{}
+		first := s.next(data)
+		for i := 0; i < 10; i++ {
+			SoMsg("alternate", s.next(data), ShouldNotEqual, first)
+			SoMsg("back", s.next(data), ShouldEqual, first)
+		}
+	})
+	Convey("New path data should be picked up", t, func() {
+		data, paths := newTestData(nil)
+		s := &scheduler{}
+		s.next(data)
+		data, paths = newTestData(map[string]pathmgr.PathHealth{"a": {Down: true}})
+		SoMsg("counts", countPaths(s, data, paths, 10), ShouldResemble,
+			map[string]int{"b": 10})
+		SoMsg("no paths", s.next(&pathmgr.SyncPathsData{}), ShouldBeNil)
+	})
+}
@@ -34,11 +34,36 @@
 //
 // Multiple networking contexts can share the same SCIOND and/or dispatcher.
 //
+// Connections keep sending on the same path for as long as it is available.
+// Connections created by Listen remember the last used path separately for
+// each remote AS, for up to PrefPathCacheSize ASes.
+//
+// Applications can control the paths of sent packets: WriteToWithPath sends a
+// single packet on a given path, SetPath sets the path for all packets of a
+// connection, and SetPathSelector installs a PathSelector that chooses the
+// path of each packet. Explicit path selection also works without SCIOND.
+//
+// Addresses returned by ReadFrom and ReadFromSCION carry the reversed path
+// of the received packet. Writing to such an address sends the packet back
+// along that path, so servers can reply to clients without SCIOND.
+//
+// Connections created via DialSCIONWithPolicy only use paths that adhere to
+// a named path policy. The policies are loaded from a policy file using
+// pathmgr.LoadPolicies and attached to the path resolver of the networking
+// context (see Network.PathResolver and pathmgr.PR.SetPolicies).
+//
 // Write calls never return SCMP errors directly. If a write call caused an
 // SCMP message to be received by the Conn, it can be inspected by calling
 // Read. In this case, the error value is non-nil and can be type asserted to
 // *OpError. Method SCMP() can be called on the error to extract the SCMP
-// header.
+// header. The class of the message is reported by IsRoutingError(),
+// IsCmnHdrError(), IsPathError() and IsExtError(), and the exact error by
+// Type(). For errors of class ROUTING, MTU() returns the MTU reported by the
+// router; for errors of class PATH, Revoked() reports whether an interface
+// was revoked, and Path() returns the path of the packet that caused the
+// error. If the Conn uses a path resolver, paths reported as unusable by PATH
+// errors are removed from its cache. To observe SCMP messages without
+// inspecting read errors, install a handler using SetSCMPHandler.
 //
 // Important: not draining SCMP errors via Read calls can cause the dispatcher
 // to shutdown the socket (see https://github.com/scionproto/scion/pull/1356).
@@ -101,6 +126,10 @@ type Network struct {
 
 // NewNetworkWithPR creates a new networking context with path resolver pr. A
 // nil path resolver means the Network will run without SCIOND.
+//
+// To get paths without SCIOND, pr can be backed by an in-process
+// segfetcher.Service, which fetches path segments directly from the path
+// servers of the local AS.
 func NewNetworkWithPR(ia addr.IA, dispatcherPath string, pr *pathmgr.PR) *Network {
 	if dispatcherPath == "" {
 		dispatcherPath = reliable.DefaultDispPath
@@ -151,6 +180,25 @@ func (n *Network) DialSCION(network string, laddr *Addr, raddr *Addr) (*Conn, er
 // Read and Write methods can be used to receive and send SCION packets.
 func (n *Network) DialSCIONWithBindSVC(network string, laddr, raddr, baddr *Addr,
 	svc addr.HostSVC) (*Conn, error) {
+	return n.dial(network, laddr, raddr, baddr, svc, "")
+}
+
+// DialSCIONWithPolicy returns a SCION connection to raddr, that only sends
+// traffic on paths that adhere to the path policy called policy. The policies
+// must have been previously set on the network's path resolver (see
+// pathmgr.PR.SetPolicies). Parameter network must be "udp4".
+func (n *Network) DialSCIONWithPolicy(network string, laddr, raddr *Addr,
+	policy string) (*Conn, error) {
+	if n.pathResolver == nil {
+		return nil, common.NewBasicError("Path policies require SCIOND", nil, "policy", policy)
+	}
+	return n.dial(network, laddr, raddr, nil, addr.SvcNone, policy)
+}
+
+// dial (internal) creates a connection to raddr. If policy is not empty, the
+// paths of the connection are filtered using the path policy called policy.
+func (n *Network) dial(network string, laddr, raddr, baddr *Addr, svc addr.HostSVC,
+	policy string) (*Conn, error) {
 	if raddr == nil {
 		return nil, common.NewBasicError("Unable to dial to nil remote", nil)
 	}
@@ -160,8 +208,13 @@ func (n *Network) DialSCIONWithBindSVC(network string, laddr, raddr, baddr *Addr
 	}
 	conn.raddr = raddr.Copy()
 	if n.pathResolver != nil {
-		conn.sp, err = n.pathResolver.Watch(conn.laddr.IA, conn.raddr.IA)
+		if policy == "" {
+			conn.sp, err = n.pathResolver.Watch(conn.laddr.IA, conn.raddr.IA)
+		} else {
+			conn.sp, err = n.pathResolver.WatchPolicy(conn.laddr.IA, conn.raddr.IA, policy)
+		}
 		if err != nil {
+			conn.Close()
 			return nil, common.NewBasicError("Unable to establish path", err)
 		}
 	}
@@ -207,6 +260,7 @@ func (n *Network) ListenSCIONWithBindSVC(network string, laddr, baddr *Addr,
 		scionNet:   n,
 		recvBuffer: make(common.RawBytes, BufSize),
 		sendBuffer: make(common.RawBytes, BufSize),
+		prefPaths:  newPrefPathCache(),
 		svc:        svc}
 
 	// Initialize local bind address
@@ -0,0 +1,174 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package squic
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"time"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/crypto"
+	"github.com/scionproto/scion/go/lib/crypto/cert"
+	"github.com/scionproto/scion/go/lib/infra"
+)
+
+const (
+	// Maximum time to get the TRC needed to verify a peer
+	verifyTimeout = 5 * time.Second
+	// Prefix of the signature input of endorsements, so that the signature
+	// cannot be used in a different context
+	endorsementContext = "SCION squic TLS key endorsement\x00"
+)
+
+// oidEndorsement identifies the X.509 extension that carries the endorsement
+// of the TLS key by the AS.
+var oidEndorsement = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 55324, 1, 1}
+
+// AuthConfig contains the information needed to authenticate squic sessions
+// using the SCION control-plane PKI.
+type AuthConfig struct {
+	// Certificate chain of the local AS
+	Chain *cert.Chain
+	// Private signing key of the local AS, matching Chain.Leaf.SubjectSignKey
+	SignKey common.RawBytes
+	// Trust store used to get the TRCs needed to verify the chains of peers
+	TrustStore infra.TrustStore
+	// If true, listeners also require clients to authenticate
+	MutualAuth bool
+}
+
+// endorsement is the content of the endorsement extension.
+type endorsement struct {
+	// Compressed certificate chain of the AS
+	Chain []byte
+	// Signature of the AS over the public key of the TLS certificate
+	Signature []byte
+}
+
+// newTLSCert creates a self-signed TLS certificate with a fresh key. The
+// certificate carries the certificate chain of the local AS, and a signature
+// of the AS over the public key of the certificate. The certificate expires
+// together with the leaf certificate of the chain.
+func newTLSCert(cfg *AuthConfig) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, common.NewBasicError("Unable to generate TLS key", err)
+	}
+	spki, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return tls.Certificate{}, common.NewBasicError("Unable to marshal TLS public key", err)
+	}
+	sig, err := crypto.Sign(endorsementInput(spki), cfg.SignKey, cfg.Chain.Leaf.SignAlgorithm)
+	if err != nil {
+		return tls.Certificate{}, common.NewBasicError("Unable to sign TLS public key", err)
+	}
+	rawChain, err := cfg.Chain.Compress()
+	if err != nil {
+		return tls.Certificate{}, common.NewBasicError("Unable to compress chain", err)
+	}
+	ext, err := asn1.Marshal(endorsement{Chain: rawChain, Signature: sig})
+	if err != nil {
+		return tls.Certificate{}, common.NewBasicError("Unable to marshal endorsement", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, common.NewBasicError("Unable to generate serial number", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cfg.Chain.Leaf.Subject.String()},
+		// The TLS 1.3 server selects its certificate by the SNI of the client.
+		DNSNames:  []string{dummyHost},
+		NotBefore: time.Now().Add(-time.Minute),
+		NotAfter:  time.Unix(int64(cfg.Chain.Leaf.ExpirationTime), 0),
+		KeyUsage:  x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth,
+		},
+		ExtraExtensions: []pkix.Extension{{Id: oidEndorsement, Value: ext}},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, common.NewBasicError("Unable to create TLS certificate", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{raw}, PrivateKey: key}, nil
+}
+
+// verifyRawPeer parses the DER encoded leaf of rawCerts and checks that it is
+// endorsed by AS ia. It is used as tls.Config.VerifyPeerCertificate, so that
+// the handshake fails if the peer cannot be authenticated.
+func verifyRawPeer(rawCerts [][]byte, ia addr.IA, trustStore infra.TrustStore) error {
+	if len(rawCerts) == 0 {
+		return common.NewBasicError("No peer certificate", nil)
+	}
+	leaf, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return common.NewBasicError("Unable to parse peer certificate", err)
+	}
+	return verifyPeer([]*x509.Certificate{leaf}, ia, trustStore)
+}
+
+// verifyPeer checks that the leaf of certs is endorsed by AS ia. The
+// certificate chain of the AS is verified against the TRC of its ISD.
+func verifyPeer(certs []*x509.Certificate, ia addr.IA, trustStore infra.TrustStore) error {
+	if len(certs) == 0 {
+		return common.NewBasicError("No peer certificate", nil)
+	}
+	var ext []byte
+	for _, e := range certs[0].Extensions {
+		if e.Id.Equal(oidEndorsement) {
+			ext = e.Value
+		}
+	}
+	if ext == nil {
+		return common.NewBasicError("Peer certificate not endorsed by AS", nil)
+	}
+	var e endorsement
+	if rest, err := asn1.Unmarshal(ext, &e); err != nil || len(rest) != 0 {
+		return common.NewBasicError("Unable to parse endorsement", err)
+	}
+	chain, err := cert.ChainFromRaw(e.Chain, true)
+	if err != nil {
+		return common.NewBasicError("Unable to parse certificate chain", err)
+	}
+	ctx, cancelF := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancelF()
+	trc, err := trustStore.GetValidTRC(ctx, ia.I, ia.I)
+	if err != nil {
+		return common.NewBasicError("Unable to get TRC", err, "isd", ia.I)
+	}
+	if err := chain.Verify(ia, trc); err != nil {
+		return common.NewBasicError("Invalid certificate chain", err, "ia", ia)
+	}
+	err = crypto.Verify(endorsementInput(certs[0].RawSubjectPublicKeyInfo), e.Signature,
+		chain.Leaf.SubjectSignKey, chain.Leaf.SignAlgorithm)
+	if err != nil {
+		return common.NewBasicError("Invalid endorsement", err, "ia", ia)
+	}
+	return nil
+}
+
+func endorsementInput(spki []byte) common.RawBytes {
+	return append([]byte(endorsementContext), spki...)
+}
@@ -0,0 +1,259 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package squic
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/scionproto/scion/go/lib/addr"
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/crypto"
+	"github.com/scionproto/scion/go/lib/crypto/cert"
+	"github.com/scionproto/scion/go/lib/crypto/trc"
+	"github.com/scionproto/scion/go/lib/infra"
+	"github.com/scionproto/scion/go/lib/snet"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+var (
+	coreIA = xtest.MustParseIA("1-ff00:0:110")
+	leafIA = xtest.MustParseIA("1-ff00:0:111")
+)
+
+// testTrustStore returns the same TRC for all ISDs.
+type testTrustStore struct {
+	infra.TrustStore
+	trc *trc.TRC
+}
+
+func (s *testTrustStore) GetValidTRC(ctx context.Context, isd addr.ISD,
+	trail ...addr.ISD) (*trc.TRC, error) {
+
+	return s.trc, nil
+}
+
+func newKeys(t *testing.T) (common.RawBytes, common.RawBytes) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	xtest.FailOnErr(t, err)
+	return common.RawBytes(pub), common.RawBytes(priv)
+}
+
+// newTestAuthConfig returns an authentication config for an AS with a chain
+// issued by coreIA, and a trust store containing the TRC of coreIA.
+func newTestAuthConfig(t *testing.T, subject addr.IA) *AuthConfig {
+	t.Helper()
+	now := uint32(time.Now().Unix())
+	onlinePub, onlinePriv := newKeys(t)
+	issuerPub, issuerPriv := newKeys(t)
+	leafPub, leafPriv := newKeys(t)
+	issuer := &cert.Certificate{
+		CanIssue:       true,
+		ExpirationTime: now + 3600,
+		Issuer:         coreIA,
+		IssuingTime:    now - 60,
+		SignAlgorithm:  crypto.Ed25519,
+		Subject:        coreIA,
+		SubjectSignKey: issuerPub,
+		Version:        1,
+	}
+	xtest.FailOnErr(t, issuer.Sign(onlinePriv, crypto.Ed25519))
+	leaf := &cert.Certificate{
+		ExpirationTime: now + 1800,
+		Issuer:         coreIA,
+		IssuingTime:    now - 30,
+		SignAlgorithm:  crypto.Ed25519,
+		Subject:        subject,
+		SubjectSignKey: leafPub,
+		Version:        1,
+	}
+	xtest.FailOnErr(t, leaf.Sign(issuerPriv, crypto.Ed25519))
+	return &AuthConfig{
+		Chain:   &cert.Chain{Leaf: leaf, Issuer: issuer},
+		SignKey: leafPriv,
+		TrustStore: &testTrustStore{
+			trc: &trc.TRC{
+				CoreASes: map[addr.IA]*trc.CoreAS{
+					coreIA: {OnlineKey: onlinePub, OnlineKeyAlg: crypto.Ed25519},
+				},
+				ExpirationTime: now + 7200,
+			},
+		},
+	}
+}
+
+func parseTLSCert(t *testing.T, cfg *AuthConfig) []*x509.Certificate {
+	t.Helper()
+	tlsCert, err := newTLSCert(cfg)
+	xtest.FailOnErr(t, err)
+	c, err := x509.ParseCertificate(tlsCert.Certificate[0])
+	xtest.FailOnErr(t, err)
+	return []*x509.Certificate{c}
+}
+
+func TestVerifyPeer(t *testing.T) {
+	Convey("Peers are authenticated by the endorsement of their AS", t, func() {
+		cfg := newTestAuthConfig(t, leafIA)
+		certs := parseTLSCert(t, cfg)
+		Convey("Endorsed by the expected AS", func() {
+			SoMsg("err", verifyPeer(certs, leafIA, cfg.TrustStore), ShouldBeNil)
+		})
+		Convey("Endorsed by a different AS", func() {
+			err := verifyPeer(certs, xtest.MustParseIA("1-ff00:0:112"), cfg.TrustStore)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+		Convey("Chain not verifiable with the TRC", func() {
+			other := newTestAuthConfig(t, leafIA)
+			SoMsg("err", verifyPeer(certs, leafIA, other.TrustStore), ShouldNotBeNil)
+		})
+		Convey("Endorsement signed with the wrong key", func() {
+			_, cfg.SignKey = newKeys(t)
+			certs := parseTLSCert(t, cfg)
+			SoMsg("err", verifyPeer(certs, leafIA, cfg.TrustStore), ShouldNotBeNil)
+		})
+		Convey("Certificate without endorsement", func() {
+			certs[0].Extensions = nil
+			SoMsg("err", verifyPeer(certs, leafIA, cfg.TrustStore), ShouldNotBeNil)
+		})
+		Convey("No certificate", func() {
+			SoMsg("err", verifyPeer(nil, leafIA, cfg.TrustStore), ShouldNotBeNil)
+		})
+	})
+}
+
+// testConn is a UDP connection that presents its peers as SCION addresses in
+// AS peerIA, such that sessions can be established without a dispatcher.
+type testConn struct {
+	net.PacketConn
+	peerIA addr.IA
+}
+
+func newTestConn(t *testing.T, peerIA addr.IA) *testConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	xtest.FailOnErr(t, err)
+	return &testConn{PacketConn: conn, peerIA: peerIA}
+}
+
+func (c *testConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, a, err := c.PacketConn.ReadFrom(b)
+	if err != nil {
+		return n, a, err
+	}
+	return n, c.scionAddr(a), nil
+}
+
+func (c *testConn) WriteTo(b []byte, a net.Addr) (int, error) {
+	sa := a.(*snet.Addr)
+	return c.PacketConn.WriteTo(b, &net.UDPAddr{IP: sa.Host.IP(), Port: int(sa.L4Port)})
+}
+
+func (c *testConn) scionAddr(a net.Addr) *snet.Addr {
+	udpAddr := a.(*net.UDPAddr)
+	return &snet.Addr{
+		IA:     c.peerIA,
+		Host:   addr.HostFromIP(udpAddr.IP),
+		L4Port: uint16(udpAddr.Port),
+	}
+}
+
+// startServer listens for sessions from clients in clientIA, and sends the
+// content of the first stream of the first accepted session to msgs.
+func startServer(t *testing.T, clientIA addr.IA) (*testConn, <-chan string) {
+	t.Helper()
+	conn := newTestConn(t, clientIA)
+	Reset(func() { conn.Close() })
+	ln, err := listen(conn)
+	xtest.FailOnErr(t, err)
+	Reset(func() { ln.Close() })
+	msgs := make(chan string, 1)
+	go func() {
+		sess, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		stream, err := sess.AcceptStream()
+		if err != nil {
+			return
+		}
+		msg, _ := ioutil.ReadAll(stream)
+		msgs <- string(msg)
+	}()
+	return conn, msgs
+}
+
+func TestDialListen(t *testing.T) {
+	Convey("Sessions are authenticated during dial and listen", t, func() {
+		oldCliTlsCfg, oldSrvTlsCfg, oldQuicCfg, oldAuthCfg := cliTlsCfg, srvTlsCfg, quicCfg, authCfg
+		Reset(func() {
+			cliTlsCfg, srvTlsCfg, quicCfg, authCfg = oldCliTlsCfg, oldSrvTlsCfg, oldQuicCfg,
+				oldAuthCfg
+		})
+		// Client and server share the TLS certificate endorsed by leafIA.
+		cfg := newTestAuthConfig(t, leafIA)
+		cfg.MutualAuth = true
+		xtest.FailOnErr(t, InitAuth(cfg))
+		cliConn := newTestConn(t, leafIA)
+		Reset(func() { cliConn.Close() })
+
+		Convey("Authenticated client and server", func() {
+			srvConn, msgs := startServer(t, leafIA)
+			sess, err := dial(cliConn, cliConn.scionAddr(srvConn.LocalAddr()))
+			SoMsg("err", err, ShouldBeNil)
+			stream, err := sess.OpenStreamSync()
+			xtest.FailOnErr(t, err)
+			_, err = stream.Write([]byte("hello"))
+			xtest.FailOnErr(t, err)
+			xtest.FailOnErr(t, stream.Close())
+			select {
+			case msg := <-msgs:
+				SoMsg("msg", msg, ShouldEqual, "hello")
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for message")
+			}
+		})
+		Convey("Server not endorsed by the AS of raddr fails the handshake", func() {
+			srvConn, _ := startServer(t, leafIA)
+			raddr := cliConn.scionAddr(srvConn.LocalAddr())
+			raddr.IA = xtest.MustParseIA("1-ff00:0:112")
+			_, err := dial(cliConn, raddr)
+			SoMsg("err", err, ShouldNotBeNil)
+		})
+		Convey("Client not endorsed by its AS is not accepted", func() {
+			srvConn, msgs := startServer(t, xtest.MustParseIA("1-ff00:0:112"))
+			sess, err := dial(cliConn, cliConn.scionAddr(srvConn.LocalAddr()))
+			SoMsg("err", err, ShouldBeNil)
+			select {
+			case <-sess.Context().Done():
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for the server to close the session")
+			}
+			select {
+			case msg := <-msgs:
+				t.Fatalf("Unexpected message: %s", msg)
+			default:
+			}
+		})
+	})
+}
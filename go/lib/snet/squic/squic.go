@@ -13,27 +13,49 @@
 // limitations under the License.
 
 // QUIC/SCION implementation.
+//
+// By default, squic sessions are not authenticated: servers use a static TLS
+// key pair (see Init), and clients do not verify it. If InitAuth is called
+// instead, TLS certificates are endorsed by the local AS, and peers are
+// authenticated using the SCION control-plane PKI: the certificate chain of
+// the peer AS is verified against the TRC of its ISD, and the peer must be
+// located in the AS that endorsed its TLS certificate. Clients always
+// authenticate servers; servers only authenticate clients if mutual
+// authentication is enabled. Authenticated sessions use the TLS 1.3 based
+// version of QUIC, as gQUIC does not support client certificates.
 package squic
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"sync"
 
 	"github.com/lucas-clemente/quic-go"
 
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/infra"
+	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/lib/snet"
 )
 
 const (
 	defKeyPath = "gen-certs/tls.key"
 	defPemPath = "gen-certs/tls.pem"
+	// Dummy hostname, as it's only used for SNI, and we're not doing TLS cert
+	// verification.
+	dummyHost = "host"
 )
 
 var (
 	// Don't verify the server's cert, as we are not using the TLS PKI.
 	cliTlsCfg = &tls.Config{InsecureSkipVerify: true}
 	srvTlsCfg = &tls.Config{}
+	// QUIC configuration, nil for defaults
+	quicCfg *quic.Config
+	// If not nil, peers are authenticated using the SCION PKI
+	authCfg *AuthConfig
 )
 
 func Init(keyPath, pemPath string) error {
@@ -51,6 +73,32 @@ func Init(keyPath, pemPath string) error {
 	return nil
 }
 
+// InitAuth enables authentication of squic sessions using the SCION
+// control-plane PKI (see package documentation). A fresh TLS key pair is
+// generated, and endorsed with the signing key of the local AS.
+func InitAuth(cfg *AuthConfig) error {
+	if cfg == nil || cfg.Chain == nil || cfg.SignKey == nil || cfg.TrustStore == nil {
+		return common.NewBasicError("squic: Incomplete authentication config", nil)
+	}
+	cert, err := newTLSCert(cfg)
+	if err != nil {
+		return common.NewBasicError("squic: Unable to create TLS certificate", err)
+	}
+	srvTlsCfg = &tls.Config{Certificates: []tls.Certificate{cert}}
+	if cfg.MutualAuth {
+		srvTlsCfg.ClientAuth = tls.RequireAnyClientCert
+	}
+	// The server's cert is verified using the SCION PKI during the handshake,
+	// see dial.
+	cliTlsCfg = &tls.Config{
+		InsecureSkipVerify: true,
+		Certificates:       []tls.Certificate{cert},
+	}
+	quicCfg = &quic.Config{Versions: []quic.VersionNumber{quic.VersionMilestone0_8_0}}
+	authCfg = cfg
+	return nil
+}
+
 func DialSCION(network *snet.Network, laddr, raddr *snet.Addr) (quic.Session, error) {
 	return DialSCIONWithBindSVC(network, laddr, raddr, nil, addr.SvcNone)
 }
@@ -61,8 +109,22 @@ func DialSCIONWithBindSVC(network *snet.Network, laddr, raddr, baddr *snet.Addr,
 	if err != nil {
 		return nil, err
 	}
-	// Use dummy hostname, as it's used for SNI, and we're not doing cert verification.
-	return quic.Dial(sconn, raddr, "host:0", cliTlsCfg, nil)
+	return dial(sconn, raddr)
+}
+
+// dial establishes a session with raddr over conn. If authentication is
+// enabled, the server must be endorsed by the AS of raddr, otherwise the
+// handshake fails.
+func dial(conn net.PacketConn, raddr *snet.Addr) (quic.Session, error) {
+	tlsCfg := cliTlsCfg
+	if authCfg != nil {
+		trustStore := authCfg.TrustStore
+		tlsCfg = cliTlsCfg.Clone()
+		tlsCfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyRawPeer(rawCerts, raddr.IA, trustStore)
+		}
+	}
+	return quic.Dial(conn, raddr, dummyHost+":0", tlsCfg, quicCfg)
 }
 
 func ListenSCION(network *snet.Network, laddr *snet.Addr) (quic.Listener, error) {
@@ -78,7 +140,103 @@ func ListenSCIONWithBindSVC(network *snet.Network, laddr, baddr *snet.Addr,
 	if err != nil {
 		return nil, err
 	}
-	return quic.Listen(sconn, srvTlsCfg, nil)
+	return listen(sconn)
+}
+
+// listen accepts sessions on conn. If mutual authentication is enabled, only
+// sessions of authenticated clients are returned by the listener.
+func listen(conn net.PacketConn) (quic.Listener, error) {
+	ln, err := quic.Listen(conn, srvTlsCfg, quicCfg)
+	if err != nil || authCfg == nil || !authCfg.MutualAuth {
+		return ln, err
+	}
+	return newAuthListener(ln, authCfg.TrustStore), nil
+}
+
+// authListener is a quic.Listener that only accepts sessions from clients that
+// successfully authenticate using the SCION PKI. The AS of a client is only
+// known once the session is established, so clients are authenticated after
+// the handshake. Each session is verified in its own goroutine, such that
+// clients that are slow to verify (e.g., because their TRC has to be fetched)
+// do not delay other clients.
+type authListener struct {
+	quic.Listener
+	trustStore infra.TrustStore
+	// Authenticated sessions, waiting to be accepted
+	sessions chan quic.Session
+	// Closed if the underlying listener fails, acceptErr is set before
+	acceptErr  error
+	acceptDone chan struct{}
+	// Closed by Close
+	closeChan chan struct{}
+	closeOnce sync.Once
+}
+
+func newAuthListener(ln quic.Listener, trustStore infra.TrustStore) *authListener {
+	l := &authListener{
+		Listener:   ln,
+		trustStore: trustStore,
+		sessions:   make(chan quic.Session),
+		acceptDone: make(chan struct{}),
+		closeChan:  make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Accept returns the next session of an authenticated client. Sessions of
+// clients that fail to authenticate are closed.
+func (l *authListener) Accept() (quic.Session, error) {
+	select {
+	case sess := <-l.sessions:
+		return sess, nil
+	case <-l.acceptDone:
+		return nil, l.acceptErr
+	}
+}
+
+func (l *authListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closeChan) })
+	return l.Listener.Close()
+}
+
+// run accepts sessions on the underlying listener, until it fails.
+func (l *authListener) run() {
+	defer log.LogPanicAndExit()
+	for {
+		sess, err := l.Listener.Accept()
+		if err != nil {
+			l.acceptErr = err
+			close(l.acceptDone)
+			return
+		}
+		go l.verify(sess)
+	}
+}
+
+// verify authenticates the client of sess. If successful, the session is
+// handed to Accept, otherwise it is closed.
+func (l *authListener) verify(sess quic.Session) {
+	defer log.LogPanicAndExit()
+	var err error
+	raddr, ok := sess.RemoteAddr().(*snet.Addr)
+	if !ok {
+		err = common.NewBasicError("Unexpected remote address type", nil,
+			"type", common.TypeOf(sess.RemoteAddr()))
+	} else {
+		err = verifyPeer(sess.ConnectionState().PeerCertificates, raddr.IA, l.trustStore)
+	}
+	if err != nil {
+		log.Info("squic: Unable to authenticate client", "raddr", sess.RemoteAddr(),
+			"err", err)
+		sess.Close(err)
+		return
+	}
+	select {
+	case l.sessions <- sess:
+	case <-l.closeChan:
+		sess.Close(nil)
+	}
 }
 
 func sListen(network *snet.Network, laddr, baddr *snet.Addr,
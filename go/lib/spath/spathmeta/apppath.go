@@ -18,6 +18,7 @@ package spathmeta
 import (
 	"crypto/sha256"
 	"encoding/binary"
+	"sort"
 	"strings"
 
 	"github.com/scionproto/scion/go/lib/common"
@@ -58,8 +59,9 @@ func (aps AppPathSet) Copy() AppPathSet {
 }
 
 // GetAppPath returns an AppPath from the set. It first tries to find
-// a path with key pref; if one cannot be found, an arbitrary one
-// is returned.
+// a path with key pref; if one cannot be found, the path with the fewest
+// interfaces is returned. Ties are broken by key, so the fallback is the same
+// for equal sets.
 func (aps AppPathSet) GetAppPath(pref PathKey) *AppPath {
 	if len(pref) > 0 {
 		ap, ok := aps[pref]
@@ -67,10 +69,43 @@ func (aps AppPathSet) GetAppPath(pref PathKey) *AppPath {
 			return ap
 		}
 	}
-	for _, v := range aps {
-		return v
+	var best PathKey
+	for k, v := range aps {
+		if best == "" || lessHops(v, aps[best]) || (!lessHops(aps[best], v) && k < best) {
+			best = k
+		}
+	}
+	return aps[best]
+}
+
+// Sorted returns the paths in aps ordered by less. Paths that are equivalent
+// according to less are ordered by key, so the order is deterministic. If
+// less is nil, the paths are ordered by key only.
+func (aps AppPathSet) Sorted(less func(a, b *AppPath) bool) []*AppPath {
+	keys := make([]PathKey, 0, len(aps))
+	for k := range aps {
+		keys = append(keys, k)
 	}
-	return nil
+	sort.Slice(keys, func(i, j int) bool {
+		if less != nil {
+			if less(aps[keys[i]], aps[keys[j]]) {
+				return true
+			}
+			if less(aps[keys[j]], aps[keys[i]]) {
+				return false
+			}
+		}
+		return keys[i] < keys[j]
+	})
+	paths := make([]*AppPath, len(keys))
+	for i, k := range keys {
+		paths[i] = aps[k]
+	}
+	return paths
+}
+
+func lessHops(a, b *AppPath) bool {
+	return len(a.Entry.Path.Interfaces) < len(b.Entry.Path.Interfaces)
 }
 
 func (aps AppPathSet) String() string {
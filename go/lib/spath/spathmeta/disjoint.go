@@ -0,0 +1,84 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spathmeta
+
+import (
+	"sort"
+
+	"github.com/scionproto/scion/go/lib/sciond"
+)
+
+// DisjointIndices selects up to k of the interface sequences in paths such
+// that the selected sequences share as few interfaces as possible, and
+// returns their indices in order of selection. Selection is greedy: each step
+// picks the sequence sharing the fewest interfaces with the sequences picked
+// so far. Ties are broken in favor of the lower index, so callers can pass
+// the paths in order of preference. If k is 0, all sequences are ordered.
+func DisjointIndices(paths [][]sciond.PathInterface, k int) []int {
+	if k <= 0 || k > len(paths) {
+		k = len(paths)
+	}
+	picked := make([]bool, len(paths))
+	used := make(map[sciond.PathInterface]int)
+	var indices []int
+	for len(indices) < k {
+		best, bestShared := -1, 0
+		for i, ifaces := range paths {
+			if picked[i] {
+				continue
+			}
+			shared := 0
+			for _, iface := range ifaces {
+				shared += used[iface]
+			}
+			if best == -1 || shared < bestShared {
+				best, bestShared = i, shared
+			}
+		}
+		for _, iface := range paths[best] {
+			used[iface]++
+		}
+		picked[best] = true
+		indices = append(indices, best)
+	}
+	return indices
+}
+
+// Disjoint returns a set of up to k paths from aps that share as few
+// interfaces as possible. On ties, shorter paths are preferred. If k is 0,
+// all paths are returned.
+func (aps AppPathSet) Disjoint(k int) AppPathSet {
+	paths := make([]*AppPath, 0, len(aps))
+	for _, ap := range aps {
+		paths = append(paths, ap)
+	}
+	// Sort for deterministic tie-breaking, as map iteration order is random.
+	sort.Slice(paths, func(i, j int) bool {
+		li, lj := len(paths[i].Entry.Path.Interfaces), len(paths[j].Entry.Path.Interfaces)
+		if li != lj {
+			return li < lj
+		}
+		return paths[i].Key() < paths[j].Key()
+	})
+	ifaces := make([][]sciond.PathInterface, len(paths))
+	for i, ap := range paths {
+		ifaces[i] = ap.Entry.Path.Interfaces
+	}
+	newAPS := NewAppPathSet(nil)
+	for _, idx := range DisjointIndices(ifaces, k) {
+		newAPS[paths[idx].Key()] = paths[idx]
+	}
+	return newAPS
+}
@@ -0,0 +1,70 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spathmeta
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+func newTestIfaces(ifIDs ...common.IFIDType) []sciond.PathInterface {
+	ia := xtest.MustParseIA("1-ff00:0:110")
+	var ifaces []sciond.PathInterface
+	for _, ifID := range ifIDs {
+		ifaces = append(ifaces, sciond.PathInterface{RawIsdas: ia.IAInt(), IfID: ifID})
+	}
+	return ifaces
+}
+
+func TestDisjointIndices(t *testing.T) {
+	paths := [][]sciond.PathInterface{
+		newTestIfaces(1, 2, 3, 4),
+		newTestIfaces(1, 2, 3, 5),
+		newTestIfaces(6, 7, 8, 9),
+		newTestIfaces(1, 7, 8, 10),
+	}
+	Convey("DisjointIndices should greedily pick the least overlapping paths", t, func() {
+		SoMsg("k=2", DisjointIndices(paths, 2), ShouldResemble, []int{0, 2})
+		SoMsg("k=0", DisjointIndices(paths, 0), ShouldResemble, []int{0, 2, 1, 3})
+		SoMsg("k too large", DisjointIndices(paths, 10), ShouldResemble, []int{0, 2, 1, 3})
+		SoMsg("empty", DisjointIndices(nil, 2), ShouldBeEmpty)
+	})
+}
+
+func TestAppPathSetDisjoint(t *testing.T) {
+	Convey("Disjoint should return the least overlapping paths of the set", t, func() {
+		reply := &sciond.PathReply{}
+		for _, ifaces := range [][]sciond.PathInterface{
+			newTestIfaces(1, 2, 3, 4),
+			newTestIfaces(1, 2),
+			newTestIfaces(3, 5),
+		} {
+			reply.Entries = append(reply.Entries,
+				sciond.PathReplyEntry{Path: &sciond.FwdPathMeta{Interfaces: ifaces}})
+		}
+		aps := NewAppPathSet(reply)
+		disjoint := aps.Disjoint(2)
+		SoMsg("len", len(disjoint), ShouldEqual, 2)
+		for _, ap := range disjoint {
+			SoMsg("long path dropped", len(ap.Entry.Path.Interfaces), ShouldEqual, 2)
+		}
+		SoMsg("all", len(aps.Disjoint(0)), ShouldEqual, 3)
+	})
+}
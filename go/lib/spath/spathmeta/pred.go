@@ -16,7 +16,6 @@ package spathmeta
 
 import (
 	"encoding/json"
-	"fmt"
 	"strconv"
 	"strings"
 
@@ -67,12 +66,7 @@ func (pp *PathPredicate) Eval(path *sciond.PathReplyEntry) bool {
 }
 
 func (pp *PathPredicate) String() string {
-	var desc []string
-	for _, iface := range pp.Match {
-		isdas := iface.ISD_AS()
-		desc = append(desc, fmt.Sprintf("%d-%s#%d", isdas.I, isdas.A, iface.IfID))
-	}
-	return strings.Join(desc, ",")
+	return fmtIfaces(pp.Match, ",")
 }
 
 func (pp *PathPredicate) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,265 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spathmeta
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/scionproto/scion/go/lib/common"
+	"github.com/scionproto/scion/go/lib/sciond"
+)
+
+// A PathDenyList specifies interfaces a path must not traverse. Wildcard ISDs,
+// ASes and IFIDs are specified with 0. For example, a deny list that rejects
+// all paths passing through ISD 3 or interface 5 of AS 1-ff00:0:110 can be
+// created with:
+//     dl, err = NewPathDenyList("3-0#0,1-ff00:0:110#5")
+type PathDenyList struct {
+	Deny []sciond.PathInterface
+}
+
+func NewPathDenyList(expr string) (*PathDenyList, error) {
+	var ifaces []sciond.PathInterface
+	for _, ifaceStr := range strings.Split(expr, ",") {
+		iface, err := ppParseIface(ifaceStr)
+		if err != nil {
+			return nil, err
+		}
+		ifaces = append(ifaces, iface)
+	}
+	return &PathDenyList{Deny: ifaces}, nil
+}
+
+// Eval returns true if no interface of the path matches an entry of the deny
+// list.
+func (dl *PathDenyList) Eval(path *sciond.PathReplyEntry) bool {
+	for i := range path.Path.Interfaces {
+		for j := range dl.Deny {
+			if ppWildcardEquals(&path.Path.Interfaces[i], &dl.Deny[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (dl *PathDenyList) String() string {
+	return fmtIfaces(dl.Deny, ",")
+}
+
+func (dl *PathDenyList) MarshalJSON() ([]byte, error) {
+	return json.Marshal(dl.String())
+}
+
+func (dl *PathDenyList) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	other, err := NewPathDenyList(s)
+	if err != nil {
+		return common.NewBasicError("Unable to parse PathDenyList operand", err)
+	}
+	dl.Deny = other.Deny
+	return nil
+}
+
+// A PathSequence specifies the complete sequence of interfaces of a path,
+// where each element can be followed by a quantifier. Wildcard ISDs, ASes and
+// IFIDs are specified with 0. Supported quantifiers are ? (0 or 1 times),
+// * (0 or more times), + (1 or more times), {n} (exactly n times), {n,}
+// (n or more times) and {n,m} (between n and m times). Elements without a
+// quantifier must match exactly once. Unlike PathPredicate, gaps are not
+// allowed, i.e., the whole path must match.
+//
+// For example, a sequence that allows paths starting in AS 1-ff00:0:110 and
+// ending in ISD 2, transiting only ISD 1 and 2, can be created with:
+//     ps, err = NewPathSequence("1-ff00:0:110#0 1-0#0* 2-0#0+")
+type PathSequence struct {
+	Elems []PathSequenceElem
+}
+
+// PathSequenceElem is a single interface of a PathSequence, matching between
+// Min and Max consecutive interfaces of a path. A negative Max means
+// unbounded.
+type PathSequenceElem struct {
+	Iface sciond.PathInterface
+	Min   int
+	Max   int
+}
+
+func NewPathSequence(expr string) (*PathSequence, error) {
+	var elems []PathSequenceElem
+	for _, elemStr := range strings.Fields(expr) {
+		elem, err := parseSequenceElem(elemStr)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+	if len(elems) == 0 {
+		return nil, common.NewBasicError("Empty path sequence", nil)
+	}
+	return &PathSequence{Elems: elems}, nil
+}
+
+func parseSequenceElem(s string) (PathSequenceElem, error) {
+	elem := PathSequenceElem{Min: 1, Max: 1}
+	ifaceStr := s
+	switch {
+	case strings.HasSuffix(s, "?"):
+		ifaceStr, elem.Min, elem.Max = s[:len(s)-1], 0, 1
+	case strings.HasSuffix(s, "*"):
+		ifaceStr, elem.Min, elem.Max = s[:len(s)-1], 0, -1
+	case strings.HasSuffix(s, "+"):
+		ifaceStr, elem.Min, elem.Max = s[:len(s)-1], 1, -1
+	case strings.HasSuffix(s, "}"):
+		idx := strings.LastIndex(s, "{")
+		if idx == -1 {
+			return elem, common.NewBasicError("Unbalanced quantifier", nil, "value", s)
+		}
+		var err error
+		if elem.Min, elem.Max, err = parseQuantifier(s[idx+1 : len(s)-1]); err != nil {
+			return elem, common.NewBasicError("Invalid quantifier", err, "value", s)
+		}
+		ifaceStr = s[:idx]
+	}
+	iface, err := ppParseIface(ifaceStr)
+	if err != nil {
+		return elem, err
+	}
+	elem.Iface = iface
+	return elem, nil
+}
+
+// parseQuantifier parses the contents of a quantifier in braces.
+func parseQuantifier(s string) (int, int, error) {
+	parts := strings.SplitN(s, ",", 2)
+	min, err := strconv.ParseUint(parts[0], 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(parts) == 1 {
+		return int(min), int(min), nil
+	}
+	if parts[1] == "" {
+		return int(min), -1, nil
+	}
+	max, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil {
+		return 0, 0, err
+	}
+	if min > max {
+		return 0, 0, common.NewBasicError("Minimum exceeds maximum", nil,
+			"min", min, "max", max)
+	}
+	return int(min), int(max), nil
+}
+
+// Eval returns true if the interfaces of path match the sequence.
+func (ps *PathSequence) Eval(path *sciond.PathReplyEntry) bool {
+	ifaces := path.Path.Interfaces
+	// matched[i][j] caches whether ifaces[j:] matches ps.Elems[i:]. 0 means
+	// unknown, 1 means match, 2 means no match.
+	matched := make([][]byte, len(ps.Elems)+1)
+	for i := range matched {
+		matched[i] = make([]byte, len(ifaces)+1)
+	}
+	var match func(i, j int) bool
+	match = func(i, j int) bool {
+		if i == len(ps.Elems) {
+			return j == len(ifaces)
+		}
+		if matched[i][j] != 0 {
+			return matched[i][j] == 1
+		}
+		res := false
+		elem := &ps.Elems[i]
+		// Try all repetition counts, consuming one interface per repetition.
+		for n := 0; ; n++ {
+			if n >= elem.Min && match(i+1, j+n) {
+				res = true
+				break
+			}
+			if n == elem.Max || j+n == len(ifaces) ||
+				!ppWildcardEquals(&ifaces[j+n], &elem.Iface) {
+				break
+			}
+		}
+		matched[i][j] = 2
+		if res {
+			matched[i][j] = 1
+		}
+		return res
+	}
+	return match(0, 0)
+}
+
+func (ps *PathSequence) String() string {
+	var desc []string
+	for _, elem := range ps.Elems {
+		desc = append(desc, fmtIfaces([]sciond.PathInterface{elem.Iface}, "")+
+			elem.quantifier())
+	}
+	return strings.Join(desc, " ")
+}
+
+func (e *PathSequenceElem) quantifier() string {
+	switch {
+	case e.Min == 1 && e.Max == 1:
+		return ""
+	case e.Min == 0 && e.Max == 1:
+		return "?"
+	case e.Min == 0 && e.Max < 0:
+		return "*"
+	case e.Min == 1 && e.Max < 0:
+		return "+"
+	case e.Max < 0:
+		return fmt.Sprintf("{%d,}", e.Min)
+	case e.Min == e.Max:
+		return fmt.Sprintf("{%d}", e.Min)
+	default:
+		return fmt.Sprintf("{%d,%d}", e.Min, e.Max)
+	}
+}
+
+func (ps *PathSequence) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ps.String())
+}
+
+func (ps *PathSequence) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	other, err := NewPathSequence(s)
+	if err != nil {
+		return common.NewBasicError("Unable to parse PathSequence operand", err)
+	}
+	ps.Elems = other.Elems
+	return nil
+}
+
+func fmtIfaces(ifaces []sciond.PathInterface, sep string) string {
+	var desc []string
+	for _, iface := range ifaces {
+		isdas := iface.ISD_AS()
+		desc = append(desc, fmt.Sprintf("%d-%s#%d", isdas.I, isdas.A, iface.IfID))
+	}
+	return strings.Join(desc, sep)
+}
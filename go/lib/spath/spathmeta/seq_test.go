@@ -0,0 +1,105 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package spathmeta
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/sciond"
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+// newTestEntry returns a path entry over the interfaces described by
+// "ISD-AS#IFID" strings.
+func newTestEntry(ifaces ...string) *sciond.PathReplyEntry {
+	entry := &sciond.PathReplyEntry{Path: &sciond.FwdPathMeta{}}
+	for _, s := range ifaces {
+		iface, err := ppParseIface(s)
+		if err != nil {
+			panic(err)
+		}
+		entry.Path.Interfaces = append(entry.Path.Interfaces, iface)
+	}
+	return entry
+}
+
+var (
+	// 1-ff00:0:110 -> 1-ff00:0:120 -> 2-ff00:0:210 -> 2-ff00:0:220
+	testEntry = newTestEntry("1-ff00:0:110#1", "1-ff00:0:120#2", "1-ff00:0:120#3",
+		"2-ff00:0:210#4", "2-ff00:0:210#5", "2-ff00:0:220#6")
+)
+
+func TestPathDenyList(t *testing.T) {
+	testCases := []struct {
+		Expr     string
+		Expected bool
+	}{
+		{"3-0#0", true},
+		{"2-0#0", false},
+		{"3-0#0,1-ff00:0:120#3", false},
+		{"1-ff00:0:120#4", true},
+		{"0-0#6", false},
+	}
+	Convey("Deny lists should reject paths with matching interfaces", t, func() {
+		for _, tc := range testCases {
+			Convey(tc.Expr, func() {
+				dl, err := NewPathDenyList(tc.Expr)
+				xtest.FailOnErr(t, err)
+				SoMsg("eval", dl.Eval(testEntry), ShouldEqual, tc.Expected)
+				SoMsg("string", dl.String(), ShouldEqual, tc.Expr)
+			})
+		}
+	})
+}
+
+func TestPathSequence(t *testing.T) {
+	testCases := []struct {
+		Expr     string
+		Expected bool
+	}{
+		{"1-ff00:0:110#1 1-ff00:0:120#2 1-ff00:0:120#3 2-ff00:0:210#4 " +
+			"2-ff00:0:210#5 2-ff00:0:220#6", true},
+		{"1-ff00:0:110#0 0-0#0*", true},
+		{"1-ff00:0:110#0 1-0#0* 2-0#0+", true},
+		{"1-0#0+", false},
+		{"1-0#0{3} 2-0#0{3}", true},
+		{"1-0#0{2} 2-0#0{3}", false},
+		{"1-0#0{1,2} 2-0#0{3,}", false},
+		{"1-0#0{1,3} 2-0#0{3,}", true},
+		{"1-ff00:0:110#0 3-0#0? 0-0#0{5}", true},
+		{"0-0#0*", true},
+		{"0-0#0{0,5}", false},
+		{"1-ff00:0:110#1", false},
+	}
+	Convey("Sequences should match the whole path", t, func() {
+		for _, tc := range testCases {
+			Convey(tc.Expr, func() {
+				ps, err := NewPathSequence(tc.Expr)
+				xtest.FailOnErr(t, err)
+				SoMsg("eval", ps.Eval(testEntry), ShouldEqual, tc.Expected)
+				SoMsg("string", ps.String(), ShouldEqual, tc.Expr)
+			})
+		}
+	})
+	Convey("Invalid sequences should be rejected", t, func() {
+		for _, expr := range []string{"", "1-0", "1-0#0{", "1-0#0}", "1-0#0{3,1}",
+			"1-0#0{x}", "1-0#0**"} {
+			_, err := NewPathSequence(expr)
+			SoMsg(expr, err, ShouldNotBeNil)
+		}
+	})
+}
@@ -21,10 +21,24 @@ import (
 	"github.com/scionproto/scion/go/lib/common"
 )
 
+// Migration upgrades a database from schema version From to version From+1.
+type Migration struct {
+	// From is the schema version this migration upgrades from.
+	From int
+	// Up applies the migration. It is run inside the transaction that also
+	// updates the schema version, so a failing migration leaves the database
+	// untouched.
+	Up func(tx *sql.Tx) error
+}
+
 // New returns a new SQLite backend opening a database at the given path. If
 // no database exists a new database is be created. If the schema version of the
-// stored database is different from schemaVersion, an error is returned.
-func New(path string, schema string, schemaVersion int) (*sql.DB, error) {
+// stored database is older than schemaVersion, the pending migrations are
+// applied in order. If the stored database is newer than schemaVersion, or no
+// migration path to schemaVersion exists, an error is returned.
+func New(path string, schema string, schemaVersion int,
+	migrations ...Migration) (*sql.DB, error) {
+
 	db, err := open(path)
 	if err != nil {
 		return nil, err
@@ -33,16 +47,22 @@ func New(path string, schema string, schemaVersion int) (*sql.DB, error) {
 	var existingVersion int
 	err = db.QueryRow("PRAGMA user_version;").Scan(&existingVersion)
 	if err != nil {
+		db.Close()
 		return nil, common.NewBasicError("Failed to check schema version", err)
 	}
-	if existingVersion == 0 {
-		if err := setup(db, schema, schemaVersion); err != nil {
-			return nil, err
-		}
-	} else if existingVersion != schemaVersion {
-		return nil, common.NewBasicError("Database schema version mismatch", nil,
+	switch {
+	case existingVersion == 0:
+		err = setup(db, schema, schemaVersion)
+	case existingVersion < schemaVersion:
+		err = migrate(db, existingVersion, schemaVersion, migrations)
+	case existingVersion > schemaVersion:
+		err = common.NewBasicError("Database schema version mismatch", nil,
 			"expected", schemaVersion, "have", existingVersion)
 	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
 	return db, nil
 }
 
@@ -84,3 +104,41 @@ func setup(db *sql.DB, schema string, schemaVersion int) error {
 	}
 	return nil
 }
+
+// migrate upgrades the database from version from to version to using
+// migrations. All migrations are applied in a single transaction.
+func migrate(db *sql.DB, from, to int, migrations []Migration) error {
+	steps := make(map[int]Migration, len(migrations))
+	for _, m := range migrations {
+		if _, ok := steps[m.From]; ok {
+			return common.NewBasicError("Duplicate migration", nil, "from", m.From)
+		}
+		steps[m.From] = m
+	}
+	// Check that all steps exist before touching the database.
+	for v := from; v < to; v++ {
+		if _, ok := steps[v]; !ok {
+			return common.NewBasicError("Missing migration", nil,
+				"from", v, "have", from, "expected", to)
+		}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return common.NewBasicError("Failed to create transaction", err)
+	}
+	for v := from; v < to; v++ {
+		if err := steps[v].Up(tx); err != nil {
+			tx.Rollback()
+			return common.NewBasicError("Failed to migrate database", err, "from", v)
+		}
+	}
+	_, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", to))
+	if err != nil {
+		tx.Rollback()
+		return common.NewBasicError("Failed to write schema version", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return common.NewBasicError("Failed to commit migration", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2018 ETH Zurich, Anapaya Systems
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/scionproto/scion/go/lib/xtest"
+)
+
+const (
+	schemaV1 = `CREATE TABLE Items(Name TEXT NOT NULL);`
+	schemaV3 = `CREATE TABLE Items(Name TEXT NOT NULL, Size INTEGER NOT NULL DEFAULT 0,
+		Color TEXT NOT NULL DEFAULT 'red');`
+)
+
+var migrations = []Migration{
+	{From: 2, Up: func(tx *sql.Tx) error {
+		_, err := tx.Exec("ALTER TABLE Items ADD COLUMN Color TEXT NOT NULL DEFAULT 'red'")
+		return err
+	}},
+	{From: 1, Up: func(tx *sql.Tx) error {
+		_, err := tx.Exec("ALTER TABLE Items ADD COLUMN Size INTEGER NOT NULL DEFAULT 0")
+		return err
+	}},
+}
+
+func setupV1(t *testing.T) string {
+	path := xtest.MustTempFileName("", "sqlite-")
+	db, err := New(path, schemaV1, 1)
+	xtest.FailOnErr(t, err)
+	_, err = db.Exec("INSERT INTO Items (Name) VALUES ('a')")
+	xtest.FailOnErr(t, err)
+	db.Close()
+	return path
+}
+
+func schemaVersion(t *testing.T, db *sql.DB) int {
+	var version int
+	err := db.QueryRow("PRAGMA user_version;").Scan(&version)
+	xtest.FailOnErr(t, err)
+	return version
+}
+
+func TestNew(t *testing.T) {
+	Convey("New should set up a new database", t, func() {
+		path := xtest.MustTempFileName("", "sqlite-")
+		defer os.Remove(path)
+		db, err := New(path, schemaV3, 3, migrations...)
+		SoMsg("err", err, ShouldBeNil)
+		defer db.Close()
+		SoMsg("version", schemaVersion(t, db), ShouldEqual, 3)
+	})
+	Convey("New should apply all pending migrations in order", t, func() {
+		path := setupV1(t)
+		defer os.Remove(path)
+		db, err := New(path, schemaV3, 3, migrations...)
+		SoMsg("err", err, ShouldBeNil)
+		defer db.Close()
+		SoMsg("version", schemaVersion(t, db), ShouldEqual, 3)
+		var name, color string
+		var size int
+		err = db.QueryRow("SELECT Name, Size, Color FROM Items").Scan(&name, &size, &color)
+		SoMsg("query err", err, ShouldBeNil)
+		SoMsg("name", name, ShouldEqual, "a")
+		SoMsg("size", size, ShouldEqual, 0)
+		SoMsg("color", color, ShouldEqual, "red")
+	})
+	Convey("New should fail if a migration is missing", t, func() {
+		path := setupV1(t)
+		defer os.Remove(path)
+		db, err := New(path, schemaV3, 3, migrations[:1]...)
+		SoMsg("db", db, ShouldBeNil)
+		SoMsg("err", err, ShouldNotBeNil)
+	})
+	Convey("New should roll back if a migration fails", t, func() {
+		path := setupV1(t)
+		defer os.Remove(path)
+		failing := []Migration{
+			migrations[1],
+			{From: 2, Up: func(tx *sql.Tx) error { return errors.New("failed") }},
+		}
+		db, err := New(path, schemaV3, 3, failing...)
+		SoMsg("db", db, ShouldBeNil)
+		SoMsg("err", err, ShouldNotBeNil)
+		db, err = New(path, schemaV1, 1)
+		SoMsg("reopen err", err, ShouldBeNil)
+		defer db.Close()
+		SoMsg("version", schemaVersion(t, db), ShouldEqual, 1)
+		_, err = db.Exec("SELECT Size FROM Items")
+		SoMsg("column not added", err, ShouldNotBeNil)
+	})
+	Convey("New should fail if the database is newer", t, func() {
+		path := setupV1(t)
+		defer os.Remove(path)
+		db, err := New(path, schemaV3, 3, migrations...)
+		SoMsg("migrate err", err, ShouldBeNil)
+		db.Close()
+		db, err = New(path, schemaV1, 1)
+		SoMsg("db", db, ShouldBeNil)
+		SoMsg("err", err, ShouldNotBeNil)
+	})
+}
@@ -16,22 +16,19 @@ package servers
 
 import (
 	"context"
-	"fmt"
 	"net"
 	"time"
 
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
-	"github.com/scionproto/scion/go/lib/ctrl/path_mgmt"
 	"github.com/scionproto/scion/go/lib/infra"
-	"github.com/scionproto/scion/go/lib/infra/modules/segverifier"
+	"github.com/scionproto/scion/go/lib/infra/modules/segfetcher"
 	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/lib/overlay"
 	"github.com/scionproto/scion/go/lib/revcache"
 	"github.com/scionproto/scion/go/lib/sciond"
 	"github.com/scionproto/scion/go/lib/topology"
 	"github.com/scionproto/scion/go/proto"
-	"github.com/scionproto/scion/go/sciond/internal/fetcher"
 )
 
 const (
@@ -50,7 +47,7 @@ type Handler interface {
 // PathRequest queries. The SCIOND API spawns a goroutine with method Handle
 // for each PathRequest it receives.
 type PathRequestHandler struct {
-	Fetcher *fetcher.Fetcher
+	Fetcher *segfetcher.Fetcher
 }
 
 func (h *PathRequestHandler) Handle(transport infra.Transport, src net.Addr, pld *sciond.Pld,
@@ -282,24 +279,12 @@ func (h *RevNotificationHandler) Handle(transport infra.Transport, src net.Addr,
 	ctx, cancelF := context.WithTimeout(context.Background(), DefaultHandlerLifetime)
 	defer cancelF()
 	revNotification := pld.RevNotification
-	revReply := sciond.RevReply{}
-	revInfo, err := h.verifySRevInfo(ctx, revNotification.SRevInfo)
+	revInfo, result, err := segfetcher.VerifyRevocation(ctx, revNotification.SRevInfo)
 	if err == nil {
 		h.RevCache.Set(revcache.NewKey(revInfo.RawIsdas.IA(), common.IFIDType(revInfo.IfID)),
 			revNotification.SRevInfo, revInfo.TTL())
 	}
-	switch {
-	case isValid(err):
-		revReply.Result = sciond.RevValid
-	case isStale(err):
-		revReply.Result = sciond.RevStale
-	case isInvalid(err):
-		revReply.Result = sciond.RevInvalid
-	case isUnknown(err):
-		revReply.Result = sciond.RevUnknown
-	default:
-		panic(fmt.Sprintf("unknown error type, err = %v", err))
-	}
+	revReply := sciond.RevReply{Result: result}
 	reply := &sciond.Pld{
 		Id:       pld.Id,
 		Which:    proto.SCIONDMsg_Which_revReply,
@@ -314,48 +299,36 @@ func (h *RevNotificationHandler) Handle(transport infra.Transport, src net.Addr,
 	}
 }
 
-// verifySRevInfo first checks if the RevInfo can be extracted from sRevInfo,
-// and immediately returns with an error if it cannot. Then, revocation
-// verification is performed and the result is returned.
-func (h *RevNotificationHandler) verifySRevInfo(ctx context.Context,
-	sRevInfo *path_mgmt.SignedRevInfo) (*path_mgmt.RevInfo, error) {
-
-	// Error out immediately if RevInfo is bad
-	info, err := sRevInfo.RevInfo()
-	if err != nil {
-		return nil, common.NewBasicError("Unable to extract RevInfo", nil)
-	}
-	// FIXME(scrye): pass in trail here
-	err = segverifier.VerifyRevInfo(ctx, sRevInfo, []addr.ISD{})
-	return info, err
-}
-
-// isValid is a placeholder. It should return true if and only if revocation
-// verification ended with an outcome of valid.
-func isValid(err error) bool {
-	// FIXME(scrye): implement this once we have verification
-	return err == nil
-}
-
-// isStale is a placeholder. It should return true if and only if revocation
-// verification ended with an outcome of stale.
-func isStale(err error) bool {
-	// FIXME(scrye): implement this once we have verification
-	return false
+// RevListRequestHandler represents the shared global state for the handling of all
+// RevListReq queries. The SCIOND API spawns a goroutine with method Handle
+// for each RevListReq it receives.
+type RevListRequestHandler struct {
+	RevCache revcache.RevCache
 }
 
-// isInvalid is a placeholder. It should return true if and only if revocation
-// verification ended with an outcome of invalid.
-func isInvalid(err error) bool {
-	// FIXME(scrye): implement this once we have verification
-	return false
-}
+func (h *RevListRequestHandler) Handle(transport infra.Transport, src net.Addr, pld *sciond.Pld,
+	logger log.Logger) {
 
-// isUnknown is a placeholder. It should return true if and only if revocation
-// verification ended with an outcome of unknown.
-func isUnknown(err error) bool {
-	// FIXME(scrye): implement this once we have verification
-	return err != nil
+	ctx, cancelF := context.WithTimeout(context.Background(), DefaultHandlerLifetime)
+	defer cancelF()
+	revListReq := pld.RevListReq
+	revListReply, err := segfetcher.RevList(ctx, h.RevCache, revListReq.Isdas.IA(),
+		time.Duration(revListReq.ExpiringWithin)*time.Second)
+	if err != nil {
+		logger.Error("Unable to list revocations", "err", err)
+	}
+	reply := &sciond.Pld{
+		Id:           pld.Id,
+		Which:        proto.SCIONDMsg_Which_revListReply,
+		RevListReply: *revListReply,
+	}
+	b, err := proto.PackRoot(reply)
+	if err != nil {
+		panic(err)
+	}
+	if err := transport.SendMsgTo(ctx, b, src); err != nil {
+		logger.Warn("Unable to reply to client", "client", src, "err", err)
+	}
 }
 
 func iaInSlice(ia addr.IA, s []addr.IA) bool {
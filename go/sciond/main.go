@@ -26,26 +26,36 @@ import (
 
 	"github.com/BurntSushi/toml"
 	cache "github.com/patrickmn/go-cache"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/scionproto/scion/go/lib/addr"
 	"github.com/scionproto/scion/go/lib/common"
 	"github.com/scionproto/scion/go/lib/env"
 	"github.com/scionproto/scion/go/lib/infra/disp"
 	"github.com/scionproto/scion/go/lib/infra/messenger"
+	"github.com/scionproto/scion/go/lib/infra/modules/cleaner"
+	"github.com/scionproto/scion/go/lib/infra/modules/segfetcher"
 	"github.com/scionproto/scion/go/lib/infra/modules/trust"
 	"github.com/scionproto/scion/go/lib/infra/modules/trust/trustdb"
 	"github.com/scionproto/scion/go/lib/infra/transport"
 	"github.com/scionproto/scion/go/lib/log"
 	"github.com/scionproto/scion/go/lib/pathdb"
+	"github.com/scionproto/scion/go/lib/revcache"
+	revcachesqlite "github.com/scionproto/scion/go/lib/revcache/sqlite"
 	"github.com/scionproto/scion/go/lib/sciond"
 	"github.com/scionproto/scion/go/lib/snet"
 	"github.com/scionproto/scion/go/proto"
-	"github.com/scionproto/scion/go/sciond/internal/fetcher"
 	"github.com/scionproto/scion/go/sciond/internal/servers"
 )
 
 const (
 	ShutdownWaitTimeout = 5 * time.Second
+	// PathDBCleanupInterval is the time between two runs of the expired
+	// segment cleaner.
+	PathDBCleanupInterval = 5 * time.Minute
+	// RevCacheCleanupInterval is the time between two runs of the expired
+	// revocation cleaner of the persistent revocation cache.
+	RevCacheCleanupInterval = time.Minute
 )
 
 type Config struct {
@@ -70,6 +80,9 @@ type Config struct {
 		Bind *snet.Addr
 		// PathDB contains the file location  of the path segment database.
 		PathDB string
+		// RevCache contains the file location of the revocation cache
+		// database. If empty, revocations are only kept in memory.
+		RevCache string
 	}
 }
 
@@ -104,6 +117,11 @@ func realMain() int {
 		log.Crit("Unable to initialize pathDB", "err", err)
 		return 1
 	}
+	cleaner.InitMetrics("sciond", prometheus.Labels{"elem": config.General.ID})
+	pathDBCleaner := cleaner.New(pathDB.DeleteExpired, "pathdb", PathDBCleanupInterval,
+		log.Root())
+	go pathDBCleaner.Run()
+	defer pathDBCleaner.Stop()
 	trustDB, err := trustdb.New(config.Trust.TrustDB)
 	if err != nil {
 		log.Crit("Unable to initialize trustDB", "err", err)
@@ -143,11 +161,16 @@ func realMain() int {
 		nil,
 	)
 	trustStore.SetMessenger(msger)
-	revCache := fetcher.NewRevCache(cache.NoExpiration, time.Second)
+	revCache, closeF, err := NewRevCache()
+	if err != nil {
+		log.Crit("Unable to initialize revocation cache", "err", err)
+		return 1
+	}
+	defer closeF()
 	// Route messages to their correct handlers
 	handlers := servers.HandlerMap{
 		proto.SCIONDMsg_Which_pathReq: &servers.PathRequestHandler{
-			Fetcher: fetcher.NewFetcher(
+			Fetcher: segfetcher.NewFetcher(
 				// FIXME(scrye): This doesn't allow for topology updates. When
 				// reloading support is implemented, fresh topology information
 				// should be loaded from file.
@@ -172,6 +195,9 @@ func realMain() int {
 		proto.SCIONDMsg_Which_revNotification: &servers.RevNotificationHandler{
 			RevCache: revCache,
 		},
+		proto.SCIONDMsg_Which_revListReq: &servers.RevListRequestHandler{
+			RevCache: revCache,
+		},
 	}
 	// Create a channel where server goroutines can signal fatal errors
 	fatalC := make(chan error, 3)
@@ -219,6 +245,25 @@ func Init(configName string) error {
 	return nil
 }
 
+// NewRevCache returns the revocation cache configured in SD.RevCache. The
+// returned function stops the background cleanup and closes the database.
+func NewRevCache() (revcache.RevCache, func(), error) {
+	if config.SD.RevCache == "" {
+		return segfetcher.NewRevCache(cache.NoExpiration, time.Second), func() {}, nil
+	}
+	revCache, err := revcachesqlite.New(config.SD.RevCache)
+	if err != nil {
+		return nil, nil, err
+	}
+	revCacheCleaner := cleaner.New(revCache.DeleteExpired, "revcache",
+		RevCacheCleanupInterval, log.Root())
+	go revCacheCleaner.Run()
+	return revCache, func() {
+		revCacheCleaner.Stop()
+		revCache.Close()
+	}, nil
+}
+
 func NewServer(network string, rsockPath string, handlers servers.HandlerMap,
 	logger log.Logger) (*servers.Server, func()) {
 
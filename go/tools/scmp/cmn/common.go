@@ -128,12 +128,20 @@ func ValidateFlags() {
 }
 
 func NewSCMPPkt(t scmp.Type, info scmp.Info, ext common.Extension) *spkt.ScnPkt {
-	var exts []common.Extension
 	scmpMeta := scmp.Meta{InfoLen: uint8(info.Len() / common.LineLen)}
 	pld := make(common.RawBytes, scmp.MetaLen+info.Len())
 	scmpMeta.Write(pld)
 	info.Write(pld[scmp.MetaLen:])
 	scmpHdr := scmp.NewHdr(scmp.ClassType{Class: scmp.C_General, Type: t}, len(pld))
+	return NewSCMPPktFromHdr(scmpHdr, pld, ext)
+}
+
+// NewSCMPPktFromHdr returns a packet from Local to Remote carrying the SCMP
+// message with header scmpHdr and raw payload pld.
+func NewSCMPPktFromHdr(scmpHdr *scmp.Hdr, pld common.RawBytes,
+	ext common.Extension) *spkt.ScnPkt {
+
+	var exts []common.Extension
 	if ext != nil {
 		exts = []common.Extension{ext}
 	}
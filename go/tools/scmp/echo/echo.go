@@ -41,7 +41,8 @@ func Run() {
 func sendPkts() {
 	id = cmn.Rand()
 	info := &scmp.InfoEcho{Id: id, Seq: 0}
-	pkt := cmn.NewSCMPPkt(scmp.T_G_EchoRequest, info, nil)
+	scmpHdr, pld := scmp.NewEchoRequest(info)
+	pkt := cmn.NewSCMPPktFromHdr(scmpHdr, pld, nil)
 	b := make(common.RawBytes, cmn.Mtu)
 	nhAddr := cmn.NextHopAddr()
 
@@ -147,20 +148,9 @@ func summary() {
 }
 
 func validate(pkt *spkt.ScnPkt) (*scmp.Hdr, *scmp.InfoEcho, error) {
-	scmpHdr, ok := pkt.L4.(*scmp.Hdr)
-	if !ok {
-		return nil, nil,
-			common.NewBasicError("Not an SCMP header", nil, "type", common.TypeOf(pkt.L4))
-	}
-	scmpPld, ok := pkt.Pld.(*scmp.Payload)
-	if !ok {
-		return nil, nil,
-			common.NewBasicError("Not an SCMP payload", nil, "type", common.TypeOf(pkt.Pld))
-	}
-	info, ok := scmpPld.Info.(*scmp.InfoEcho)
-	if !ok {
-		return nil, nil,
-			common.NewBasicError("Not an Info Echo", nil, "type", common.TypeOf(info))
+	scmpHdr, info, err := scmp.ParseEcho(pkt.L4, pkt.Pld)
+	if err != nil {
+		return nil, nil, err
 	}
 	if info.Id != id {
 		return nil, nil,